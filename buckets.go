@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// arrayBucketSize is the number of elements per bucket of a large array,
+// arrays up to this length list their elements directly
+const arrayBucketSize = 1000
+
+// arrayLength counts the elements of an array without decoding them
+func arrayLength(value gjson.Result) int {
+	n := 0
+	value.ForEach(func(_, _ gjson.Result) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// addBuckets adds one collapsed node per arrayBucketSize elements of the array at prefix
+// the elements are added when the bucket is first expanded
+func (b *treeBuilder) addBuckets(parent *TreeNode, prefix jsonPath, length int) {
+	for start := 0; start < length; start += arrayBucketSize {
+		path := prefix.elements(start, min(start+arrayBucketSize, length))
+		key := path.String()
+		node, dup := b.nodes[key]
+		if !dup {
+			node = &TreeNode{
+				path:   path,
+				key:    key,
+				name:   key[len(prefix.String()):],
+				depth:  parent.depth + 1,
+				parent: parent,
+			}
+			b.nodes[key] = node
+			parent.children = append(parent.children, node)
+		}
+		node.lazy = append(node.lazy, b.doc)
+	}
+}
+
+// loadBucket adds the elements of a bucket that was not loaded yet
+func (n *TreeNode) loadBucket() {
+	if n.lazy == nil {
+		return
+	}
+	docs := n.lazy
+	n.lazy = nil
+
+	seg := n.path[len(n.path)-1]
+	array := n.path[:len(n.path)-1]
+	b := &treeBuilder{nodes: make(map[string]*TreeNode)}
+	for _, doc := range docs {
+		b.doc = doc
+		lookup(doc.jsonData, array).ForEach(func(index, val gjson.Result) bool {
+			i := int(index.Int())
+			if i >= seg.end {
+				return false
+			}
+			if i >= seg.index {
+				elementKey := array.element(i)
+				b.walk(b.add(n, elementKey, fmt.Sprintf("[%d]", i)), elementKey, val)
+			}
+			return true
+		})
+	}
+}
+
+// isBucket reports whether n groups a range of the elements of a large array
+func (n *TreeNode) isBucket() bool {
+	return len(n.path) > 0 && n.path[len(n.path)-1].kind == segmentRange
+}
+
+// bucketMatches reports whether match holds for a node the bucket would load, without loading it
+// it looks at the keys and leaf values the elements would have as nodes in the data of the bucket
+func (n *TreeNode) bucketMatches(match func(n *TreeNode) (int, bool)) bool {
+	probe := &TreeNode{}
+	test := func(path jsonPath, value gjson.Result) bool {
+		probe.key, probe.values = path.String(), nil
+		if value.Exists() && !value.IsObject() && !value.IsArray() {
+			probe.values = []string{value.String()}
+		}
+		_, ok := match(probe)
+		return ok
+	}
+
+	// visit mirrors treeBuilder.walk, nested ranges are looked into as well
+	var visit func(path jsonPath, value gjson.Result) bool
+	visit = func(path jsonPath, value gjson.Result) bool {
+		if test(path, value) {
+			return true
+		}
+		found := false
+		switch {
+		case value.IsObject():
+			value.ForEach(func(key, val gjson.Result) bool {
+				found = visit(path.child(key.String()), val)
+				return !found
+			})
+		case value.IsArray():
+			if test(path.count(), gjson.Result{}) {
+				return true
+			}
+			// the columns of the members of object elements, and the ranges of a long array
+			length := 0
+			seen := make(map[string]struct{})
+			value.ForEach(func(_, elem gjson.Result) bool {
+				length++
+				if elem.IsObject() {
+					elem.ForEach(func(key, _ gjson.Result) bool {
+						name := key.String()
+						if _, dup := seen[name]; !dup {
+							seen[name] = struct{}{}
+							found = test(path.each().child(name), gjson.Result{})
+						}
+						return !found
+					})
+				}
+				return !found
+			})
+			for start := 0; !found && length > arrayBucketSize && start < length; start += arrayBucketSize {
+				found = test(path.elements(start, min(start+arrayBucketSize, length)), gjson.Result{})
+			}
+			if !found {
+				value.ForEach(func(index, elem gjson.Result) bool {
+					found = visit(path.element(int(index.Int())), elem)
+					return !found
+				})
+			}
+		}
+		return found
+	}
+
+	seg := n.path[len(n.path)-1]
+	array := n.path[:len(n.path)-1]
+	for _, doc := range n.lazy {
+		found := false
+		lookup(doc.jsonData, array).ForEach(func(index, val gjson.Result) bool {
+			i := int(index.Int())
+			if i >= seg.index {
+				found = visit(array.element(i), val)
+			}
+			return !found && i+1 < seg.end
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// locate returns the node at path below n, loading the buckets on the way, or nil
+func (n *TreeNode) locate(path jsonPath) *TreeNode {
+	for _, child := range n.children {
+		if slices.Equal(child.path, path) {
+			return child
+		}
+		if child.path.within(path) {
+			child.loadBucket()
+			if found := child.locate(path); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// jumpTarget returns the path of the selected array, or of the nearest array holding the selection
+func (m *Model) jumpTarget() (jsonPath, bool) {
+	if m.selectedIdx < 0 || m.selectedIdx >= len(m.rows) {
+		return nil, false
+	}
+	for n := m.rows[m.selectedIdx]; n != nil && n != m.root; n = n.parent {
+		if n.kind == kindArray {
+			return n.path, true
+		}
+	}
+	// the document itself is an array
+	trimmed := bytes.TrimLeft(m.jsonData, " \t\r\n")
+	return nil, len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// startJump opens the prompt for the index to jump to
+func (m *Model) startJump() {
+	if m.recordView == viewRecords {
+		m.status = "open a record to jump to an index"
+		return
+	}
+	path, ok := m.jumpTarget()
+	if !ok {
+		m.status = "select an array to jump to an index"
+		return
+	}
+	m.edit = &editPrompt{op: editJump, path: path, prevCursor: m.searchCursor}
+	m.searchCursor = 0
+}
+
+// jumpToIndex selects the element at the typed index of the array at path
+func (m *Model) jumpToIndex(path jsonPath, text string) error {
+	index, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || index < 0 {
+		return fmt.Errorf("invalid index %q", text)
+	}
+	target := m.root.locate(path.element(index))
+	if target == nil {
+		return fmt.Errorf("the array has no element %d", index)
+	}
+	m.showNode(target)
+	return nil
+}
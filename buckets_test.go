@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// bucketDoc returns a document whose items array holds n objects with an id
+func bucketDoc(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"id":%d}`, i)
+	}
+	return `{"items":[` + strings.Join(items, ",") + `]}`
+}
+
+func TestArrayBuckets(t *testing.T) {
+	m := newTestModel(t, bucketDoc(2500))
+	want := []string{"items", "items.#", "items[].id", "items[0..999]", "items[1000..1999]", "items[2000..2499]"}
+	if got := rowKeys(m); !slices.Equal(got, want) {
+		t.Fatalf("rows = %v, want %v", got, want)
+	}
+	if len(m.jp.keys) != 3 {
+		t.Errorf("key index holds %d keys, want only items, its length and its column", len(m.jp.keys))
+	}
+
+	// expanding every level leaves unopened buckets alone
+	m = send(m, tea.KeyPressMsg{Code: '9', Mod: tea.ModAlt})
+	if got := rowKeys(m); !slices.Equal(got, want) {
+		t.Errorf("alt+9 rows = %v, want %v", got, want)
+	}
+
+	// a bucket loads its elements when it is opened
+	m.selectedIdx = 4
+	m = send(m, tea.KeyPressMsg{Code: tea.KeyRight})
+	bucket := m.rows[4]
+	if bucket.lazy != nil || len(bucket.children) != 1000 {
+		t.Fatalf("opened bucket has %d children, lazy %v", len(bucket.children), bucket.lazy != nil)
+	}
+	rows := rowKeys(m)
+	if rows[5] != "items[1000]" || rows[6] != "items[1000].id" || !slices.Contains(rows, "items[1999].id") {
+		t.Errorf("rows after opening the bucket start with %v", rows[:7])
+	}
+	if results, _ := queryResults(bucket.key, m.jsonData); len(results) != 1000 || results[0].Get("id").Int() != 1000 {
+		t.Errorf("the bucket resolves to %d values", len(results))
+	}
+}
+
+// closedBuckets returns the keys of the buckets below the root that were not opened
+func closedBuckets(m Model) []string {
+	var keys []string
+	walkTree(m.root, func(n *TreeNode) {
+		if n.lazy != nil {
+			keys = append(keys, n.key)
+		}
+	})
+	return keys
+}
+
+func TestSearchUnopenedBuckets(t *testing.T) {
+	tests := []struct {
+		name   string
+		values bool
+		query  string
+		want   string   // selected row, empty for no match
+		closed []string // buckets left unopened
+	}{
+		{"key", false, "items[1500]", "items[1500]", []string{"items[0..999]", "items[2000..2499]"}},
+		{"value", true, "2345", "items[2345].id", []string{"items[0..999]", "items[1000..1999]"}},
+		{"no match", false, "zzz", "", []string{"items[0..999]", "items[1000..1999]", "items[2000..2499]"}},
+	}
+	for _, tt := range tests {
+		m := newTestModel(t, bucketDoc(2500))
+		if tt.values {
+			m = send(m, ctrlKey('s'))
+		}
+		m = typeText(m, tt.query)
+		got := ""
+		if len(m.rows) > 0 {
+			got = m.rows[m.selectedIdx].key
+		}
+		if got != tt.want {
+			t.Errorf("%s: selected %q, want %q", tt.name, got, tt.want)
+		}
+		if closed := closedBuckets(m); !slices.Equal(closed, tt.closed) {
+			t.Errorf("%s: unopened buckets %v, want %v", tt.name, closed, tt.closed)
+		}
+		if m.status != "" {
+			t.Errorf("%s: status %q", tt.name, m.status)
+		}
+	}
+}
+
+func TestJumpToIndex(t *testing.T) {
+	m := newTestModel(t, bucketDoc(2500))
+	enter := tea.KeyPressMsg{Code: tea.KeyEnter}
+	m = send(typeText(send(m, ctrlKey('g')), "2400"), enter)
+	if got := m.rows[m.selectedIdx].key; got != "items[2400]" {
+		t.Fatalf("selected %s, want items[2400]", got)
+	}
+	if m.status != "" {
+		t.Errorf("status %q", m.status)
+	}
+
+	// the bucket stays open when the element is edited
+	m = send(m, tea.KeyPressMsg{Code: tea.KeyDown}, ctrlKey('v'))
+	m = send(typeText(send(m, tea.KeyPressMsg{Code: tea.KeyBackspace}, tea.KeyPressMsg{Code: tea.KeyBackspace},
+		tea.KeyPressMsg{Code: tea.KeyBackspace}, tea.KeyPressMsg{Code: tea.KeyBackspace}), "-1"), enter)
+	if got := m.rows[m.selectedIdx].key; got != "items[2400].id" || lookup(m.jp.jsonData, m.rows[m.selectedIdx].path).Raw != "-1" {
+		t.Errorf("after the edit selected %s", got)
+	}
+
+	for _, tt := range []struct{ text, status string }{
+		{"x", `invalid index "x"`},
+		{"2500", "the array has no element 2500"},
+	} {
+		m = send(typeText(send(m, ctrlKey('g')), tt.text), enter)
+		if m.status != tt.status {
+			t.Errorf("jump to %s: status %q, want %q", tt.text, m.status, tt.status)
+		}
+	}
+
+	// jumping needs an array
+	m = send(newTestModel(t, `{"a":1}`), ctrlKey('g'))
+	if m.edit != nil || m.status != "select an array to jump to an index" {
+		t.Errorf("jump outside an array: prompt %v, status %q", m.edit, m.status)
+	}
+}
+
+func TestLimitLines(t *testing.T) {
+	short := strings.Repeat("x\n", 10)
+	if got := limitLines(short); got != short {
+		t.Errorf("limitLines cut a short value: %q", got)
+	}
+	long := strings.Repeat("x\n", extractMaxLines+99) + "x"
+	got := limitLines(long)
+	if lines := strings.Count(got, "\n"); lines != extractMaxLines {
+		t.Errorf("limitLines kept %d lines, want %d", lines, extractMaxLines)
+	}
+	if !strings.HasSuffix(got, "… 100 more lines, open a range of the array to see them") {
+		t.Errorf("limitLines ends with %q", got[len(got)-60:])
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// outputOptions controls how query results are printed in non-interactive mode
+type outputOptions struct {
+	raw     bool // print strings without JSON quotes
+	compact bool // print objects and arrays on a single line
+}
+
+// runQuery evaluates a query the same way the TUI does and writes every result on its own line
+// JSON Lines input is queried record by record
+func runQuery(w io.Writer, query string, jp *JSONProcessor, opts outputOptions) error {
+	if _, err := parsePath(query); err != nil {
+		return fmt.Errorf("invalid query %q: %w", query, err)
+	}
+	results, ok := jp.query(query)
+	if !ok {
+		return fmt.Errorf("query failed: no matching data found for %q", query)
+	}
+	for _, result := range results {
+		if _, err := fmt.Fprintln(w, formatOutput(result, opts)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatOutput renders a single result according to the output options
+func formatOutput(result gjson.Result, opts outputOptions) string {
+	if !result.Exists() {
+		return "null"
+	}
+	if result.IsObject() || result.IsArray() {
+		var buf bytes.Buffer
+		var err error
+		if opts.compact {
+			err = json.Compact(&buf, []byte(result.Raw))
+		} else {
+			err = json.Indent(&buf, []byte(result.Raw), "", "  ")
+		}
+		if err != nil {
+			return result.Raw
+		}
+		return buf.String()
+	}
+	if opts.raw {
+		return result.String()
+	}
+	return result.Raw
+}
+
+// Values accepted by the -p flag
+const (
+	printKey   = "key"
+	printValue = "value"
+	printBoth  = "both"
+)
+
+// printSelection writes the key and/or value picked in the TUI
+// with printBoth the key and the value are separated by a tab
+func printSelection(w io.Writer, sel *Selection, mode string, opts outputOptions) error {
+	if mode == printKey {
+		_, err := fmt.Fprintln(w, sel.key)
+		return err
+	}
+
+	var values []string
+	if sel.query {
+		results, _, err := sel.source.evalJQ(sel.key)
+		if err != nil {
+			return err
+		}
+		for _, v := range results {
+			values = append(values, formatJQOutput(v, opts))
+		}
+	} else {
+		results, ok := sel.source.query(sel.key)
+		if !ok {
+			return fmt.Errorf("query failed: no matching data found for %q", sel.key)
+		}
+		for _, result := range results {
+			values = append(values, formatOutput(result, opts))
+		}
+	}
+
+	value := strings.Join(values, "\n")
+	if mode == printBoth {
+		value = sel.key + "\t" + value
+	}
+	_, err := fmt.Fprintln(w, value)
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+const cliDoc = `{"name":"jex","tags":["a","b"],"owner":{"id":7,"active":true},"items":[{"id":1},{"id":2}]}`
+
+func TestRunQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		opts  outputOptions
+		want  string
+	}{
+		{"name", outputOptions{}, "\"jex\"\n"},
+		{"name", outputOptions{raw: true}, "jex\n"},
+		{"owner.id", outputOptions{}, "7\n"},
+		{"tags[1]", outputOptions{raw: true}, "b\n"},
+		{"owner", outputOptions{}, "{\n  \"id\": 7,\n  \"active\": true\n}\n"},
+		{"owner", outputOptions{compact: true}, "{\"id\":7,\"active\":true}\n"},
+		{"items[].id", outputOptions{}, "1\n2\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := runQuery(&out, tt.query, &JSONProcessor{jsonData: []byte(cliDoc)}, tt.opts); err != nil {
+			t.Errorf("runQuery(%s, %+v): %v", tt.query, tt.opts, err)
+			continue
+		}
+		if out.String() != tt.want {
+			t.Errorf("runQuery(%s, %+v) = %q, want %q", tt.query, tt.opts, out.String(), tt.want)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := runQuery(&out, "missing", &JSONProcessor{jsonData: []byte(cliDoc)}, outputOptions{}); err == nil || out.Len() > 0 {
+		t.Errorf("runQuery(missing) printed %q, err %v, want an error", out.String(), err)
+	}
+}
+
+func TestPrintSelection(t *testing.T) {
+	jp := &JSONProcessor{jsonData: []byte(cliDoc)}
+	tests := []struct {
+		sel  Selection
+		mode string
+		opts outputOptions
+		want string
+	}{
+		{Selection{key: "owner.id"}, printKey, outputOptions{}, "owner.id\n"},
+		{Selection{key: "owner.id"}, printValue, outputOptions{}, "7\n"},
+		{Selection{key: "name"}, printBoth, outputOptions{raw: true}, "name\tjex\n"},
+		{Selection{key: "owner"}, printValue, outputOptions{compact: true}, "{\"id\":7,\"active\":true}\n"},
+		{Selection{key: ".items[].id", query: true}, printValue, outputOptions{}, "1\n2\n"},
+		{Selection{key: ".name", query: true}, printBoth, outputOptions{raw: true}, ".name\tjex\n"},
+	}
+	for _, tt := range tests {
+		tt.sel.source = jp
+		var out bytes.Buffer
+		if err := printSelection(&out, &tt.sel, tt.mode, tt.opts); err != nil {
+			t.Errorf("printSelection(%+v, %s): %v", tt.sel, tt.mode, err)
+			continue
+		}
+		if out.String() != tt.want {
+			t.Errorf("printSelection(%+v, %s) = %q, want %q", tt.sel, tt.mode, out.String(), tt.want)
+		}
+	}
+}
+
+func TestEnterPicksSelection(t *testing.T) {
+	m := newTestModel(t, cliDoc)
+	m = send(m, tea.KeyPressMsg{Code: tea.KeyDown})
+	next, cmd := m.Update(tea.KeyPressMsg{Code: tea.KeyEnter})
+	m = next.(Model)
+	if m.picked == nil || m.picked.key != "tags" || m.picked.query {
+		t.Fatalf("enter picked %+v, want tags", m.picked)
+	}
+	if cmd == nil {
+		t.Fatal("enter did not quit")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("enter did not quit")
+	}
+
+	// in jq mode the expression is picked
+	m = newTestModel(t, cliDoc)
+	m = typeText(send(m, ctrlKey('s'), ctrlKey('s')), ".name")
+	m = send(m, tea.KeyPressMsg{Code: tea.KeyEnter})
+	if m.picked == nil || m.picked.key != ".name" || !m.picked.query {
+		t.Errorf("enter picked %+v, want the jq expression", m.picked)
+	}
+}
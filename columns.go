@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// columnStats describes a column node, a member projected over the elements of an array
+// present counts the elements holding the member out of total elements, kinds lists its types
+type columnStats struct {
+	present, total int
+	kinds          []valueKind
+}
+
+// addColumns adds a column node for every member found in the object elements of the array at prefix
+// columns are listed in order of first appearance
+func (b *treeBuilder) addColumns(parent *TreeNode, prefix jsonPath, value gjson.Result) {
+	total := 0
+	var order []string
+	stats := make(map[string]*columnStats)
+	value.ForEach(func(_, elem gjson.Result) bool {
+		total++
+		if !elem.IsObject() {
+			return true
+		}
+		seen := make(map[string]struct{})
+		elem.ForEach(func(key, val gjson.Result) bool {
+			name := key.String()
+			if _, dup := seen[name]; dup {
+				return true
+			}
+			seen[name] = struct{}{}
+			s, ok := stats[name]
+			if !ok {
+				s = &columnStats{}
+				stats[name] = s
+				order = append(order, name)
+			}
+			s.present++
+			if kind := kindOf(val); !slices.Contains(s.kinds, kind) {
+				s.kinds = append(s.kinds, kind)
+			}
+			return true
+		})
+		return true
+	})
+
+	prefixKey := prefix.String()
+	for _, name := range order {
+		path := prefix.each().child(name)
+		key := path.String()
+		node := b.add(parent, path, key[len(prefixKey):])
+		if node == nil {
+			continue
+		}
+		// JSON Lines records holding the same array add up
+		if node.column == nil {
+			node.column = &columnStats{}
+		}
+		s := stats[name]
+		node.column.present += s.present
+		node.column.total += total
+		for _, kind := range s.kinds {
+			if !slices.Contains(node.column.kinds, kind) {
+				node.column.kinds = append(node.column.kinds, kind)
+			}
+		}
+		node.kind = node.column.kinds[0]
+		if len(node.column.kinds) > 1 {
+			node.kind = kindMixed
+		}
+	}
+}
+
+// badge returns the number of elements holding the column and its types, e.g. 3/4 string|null
+func (s *columnStats) badge() string {
+	labels := make([]string, len(s.kinds))
+	for i, kind := range s.kinds {
+		labels[i] = kind.label()
+	}
+	return fmt.Sprintf("%d/%d %s", s.present, s.total, strings.Join(labels, "|"))
+}
+
+// getColumnResults renders the values of a column, labelled with the index of their element
+// elements without the member are left out
+func getColumnResults(path jsonPath, jsonData []byte) string {
+	name := path[len(path)-1].key
+	var values []string
+	lookup(jsonData, path[:len(path)-2]).ForEach(func(index, elem gjson.Result) bool {
+		if value := elem.Get(gjson.Escape(name)); elem.IsObject() && value.Exists() {
+			values = append(values, fmt.Sprintf("[%d] %s", index.Int(), formatResult(value)))
+		}
+		return true
+	})
+	if len(values) == 0 {
+		return "Query failed. No matching data found."
+	}
+	return strings.Join(values, "\n")
+}
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+const usersDoc = `{"users":[{"name":"ann","email":"a@x"},{"name":"bob","email":null},{"name":"cy","tags":["x"]}],"ids":[1,2]}`
+
+func TestColumnNodes(t *testing.T) {
+	m := newTestModel(t, usersDoc)
+	badges := make(map[string]string)
+	walkTree(m.root, func(n *TreeNode) {
+		if n.column != nil {
+			badges[n.key] = n.badge(true)
+		}
+	})
+	want := map[string]string{
+		"users[].name":  "3/3 string",
+		"users[].email": "2/3 string|null",
+		"users[].tags":  "1/3 array",
+	}
+	if len(badges) != len(want) {
+		t.Errorf("column nodes = %v, want %v", badges, want)
+	}
+	for key, badge := range want {
+		if badges[key] != badge {
+			t.Errorf("badge of %s = %q, want %q", key, badges[key], badge)
+		}
+	}
+
+	// the column is listed right after the length of its array and selects the values of every element
+	rows := rowKeys(m)
+	if rows[1] != "users.#" || rows[2] != "users[].name" {
+		t.Errorf("rows start with %v", rows[:3])
+	}
+	if got, want := getColumnResults(m.rows[2].path, m.jsonData), "[0] ann\n[1] bob\n[2] cy"; got != want {
+		t.Errorf("column values = %q, want %q", got, want)
+	}
+	// elements without the member are left out
+	if got := getColumnResults(m.rows[4].path, m.jsonData); got != "[2] [\n  \"x\"\n]" {
+		t.Errorf("column values of %s = %q", m.rows[4].key, got)
+	}
+}
+
+func TestColumnsAcrossRecords(t *testing.T) {
+	m := newTestModel(t, "{\"list\":[{\"a\":1},{\"b\":2}]}\n{\"list\":[{\"a\":\"x\"}]}\n")
+	m = send(m, ctrlKey('l'))
+	var got string
+	walkTree(m.root, func(n *TreeNode) {
+		if n.key == "list[].a" {
+			got = n.badge(true)
+		}
+	})
+	if got != "2/3 number|string" {
+		t.Errorf("union badge of list[].a = %q", got)
+	}
+}
+
+func TestIsColumn(t *testing.T) {
+	var root jsonPath
+	tests := []struct {
+		path jsonPath
+		want bool
+	}{
+		{root.child("users").each().child("name"), true},
+		{root.each().child("name"), true},
+		{root.child("users").each(), false},
+		{root.child("users").child("name"), false},
+		{root.child("a").each().child("b").each().child("c"), false},
+		{root.child("a").each().child("b").element(0), false},
+	}
+	for _, tt := range tests {
+		if got := tt.path.isColumn(); got != tt.want {
+			t.Errorf("%s.isColumn() = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
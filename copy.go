@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/itchyny/gojq"
+	"github.com/tidwall/gjson"
+)
+
+// copyPrompt lists the keys accepted after ctrl+x
+const copyPrompt = "copy: k key · g gjson · q jq · $ JSONPath · / JSON Pointer · v value · c compact value"
+
+// statusTimeout is how long a confirmation stays in the status line
+const statusTimeout = 2 * time.Second
+
+// clearStatusMsg clears the status message it was scheduled for
+type clearStatusMsg struct {
+	id int
+}
+
+// flash shows a status message and returns a command that clears it after statusTimeout
+func (m *Model) flash(text string) tea.Cmd {
+	m.status = text
+	m.statusID++
+	id := m.statusID
+	return tea.Tick(statusTimeout, func(time.Time) tea.Msg {
+		return clearStatusMsg{id: id}
+	})
+}
+
+// copyToClipboard returns a command that sets the clipboard with an OSC 52 sequence
+// the sequence reaches the local terminal over SSH, tmux and screen need it wrapped to pass it on
+func copyToClipboard(text string) tea.Cmd {
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+	return tea.Raw(seq.String())
+}
+
+// copySelection copies the selected path or the extractor content in the format picked by key
+func (m *Model) copySelection(key string) tea.Cmd {
+	var text, what string
+	var err error
+	switch key {
+	case "v", "c":
+		var values []string
+		values, err = m.extractValues(key == "c")
+		text, what = strings.Join(values, "\n"), "value"
+	case "k", "g", "q", "$", "/":
+		path, ok := m.selectedPath()
+		if !ok {
+			return m.flash("nothing selected")
+		}
+		switch key {
+		case "k":
+			text, what = path.String(), "key"
+		case "g":
+			text, what = path.gjson(), "gjson path"
+		case "q":
+			text, what = path.jq(), "jq path"
+		case "$":
+			text, err = path.jsonPathQuery()
+			what = "JSONPath"
+		case "/":
+			text, err = path.pointer()
+			what = "JSON Pointer"
+		}
+	default:
+		return nil
+	}
+	if err != nil {
+		return m.flash(err.Error())
+	}
+
+	preview := text
+	if runes := []rune(preview); len(runes) > valueSnippetWidth || strings.Contains(preview, "\n") {
+		preview = fmt.Sprintf("%d bytes", len(text))
+	}
+	return tea.Batch(copyToClipboard(text), m.flash(fmt.Sprintf("copied %s: %s", what, preview)))
+}
+
+// extractValues returns the values shown in the extractor as JSON, one per result
+func (m *Model) extractValues(compact bool) ([]string, error) {
+	results, err := m.selectionResults()
+	if err != nil {
+		return nil, err
+	}
+	opts := outputOptions{compact: compact}
+	out := make([]string, len(results))
+	for i, result := range results {
+		out[i] = formatOutput(result, opts)
+	}
+	return out, nil
+}
+
+// selectionResults returns the values shown in the extractor, the jq results or those of the selected node
+func (m *Model) selectionResults() ([]gjson.Result, error) {
+	if m.searchMode == searchJQ {
+		values, _, err := m.source().evalJQ(m.jqQuery)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]gjson.Result, len(values))
+		for i, v := range values {
+			raw, err := gojq.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = gjson.ParseBytes(raw)
+		}
+		return results, nil
+	}
+
+	if m.selectedIdx < 0 || m.selectedIdx >= len(m.rows) {
+		return nil, errors.New("nothing selected")
+	}
+	key := m.rows[m.selectedIdx].key
+	var results []gjson.Result
+	switch m.recordView {
+	case viewRecords:
+		results = []gjson.Result{gjson.ParseBytes(m.jp.records[recordIndex(key)].jsonData)}
+	case viewUnion:
+		results, _ = m.jp.query(key)
+	default:
+		results, _ = queryResults(key, m.jsonData)
+	}
+	if len(results) == 0 {
+		return nil, errors.New("the selection has no value")
+	}
+	return results, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// copied runs the command returned for a copy and returns the escape sequence written to the terminal
+func copied(t *testing.T, cmd tea.Cmd) string {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("no command returned")
+	}
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok {
+		return ""
+	}
+	for _, c := range batch {
+		if raw, ok := c().(tea.RawMsg); ok {
+			return raw.Msg.(string)
+		}
+	}
+	return ""
+}
+
+func TestCopySelection(t *testing.T) {
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm-256color")
+	const doc = `{"metadata":{"labels":{"app.kubernetes.io/name":"web"}},"items":[1,2]}`
+	tests := []struct {
+		row, key string
+		want     string
+		status   string
+	}{
+		{"metadata.labels[\"app.kubernetes.io/name\"]", "k", `metadata.labels["app.kubernetes.io/name"]`, "copied key: "},
+		{"metadata.labels[\"app.kubernetes.io/name\"]", "g", `metadata.labels.app\.kubernetes\.io\/name`, "copied gjson path: "},
+		{"metadata.labels[\"app.kubernetes.io/name\"]", "q", `.metadata.labels["app.kubernetes.io/name"]`, "copied jq path: "},
+		{"metadata.labels[\"app.kubernetes.io/name\"]", "$", `$.metadata.labels['app.kubernetes.io/name']`, "copied JSONPath: "},
+		{"metadata.labels[\"app.kubernetes.io/name\"]", "/", "/metadata/labels/app.kubernetes.io~1name", "copied JSON Pointer: "},
+		{"items", "v", "[\n  1,\n  2\n]", "copied value: 12 bytes"},
+		{"items", "c", "[1,2]", "copied value: [1,2]"},
+	}
+	for _, tt := range tests {
+		m := newTestModel(t, doc)
+		m.selectedIdx = slices.Index(rowKeys(m), tt.row)
+		m = send(m, ctrlKey('x'))
+		if !m.copyPending || m.status != copyPrompt {
+			t.Fatalf("ctrl+x: pending %v, status %q", m.copyPending, m.status)
+		}
+		next, cmd := m.Update(tea.KeyPressMsg{Code: rune(tt.key[0]), Text: tt.key})
+		m = next.(Model)
+		want := osc52.New(tt.want).String()
+		if got := copied(t, cmd); got != want {
+			t.Errorf("%s %s: copied %q, want %q", tt.row, tt.key, got, want)
+		}
+		if !strings.HasPrefix(m.status, tt.status) || m.copyPending {
+			t.Errorf("%s %s: status %q, want it to start with %q", tt.row, tt.key, m.status, tt.status)
+		}
+	}
+
+	// the array length has no JSON Pointer
+	m := newTestModel(t, doc)
+	m.selectedIdx = slices.Index(rowKeys(m), "items.#")
+	m = send(m, ctrlKey('x'), tea.KeyPressMsg{Code: '/', Text: "/"})
+	if !strings.Contains(m.status, "single value") {
+		t.Errorf("status = %q, want the pointer error", m.status)
+	}
+}
+
+func TestCopyStatusTimeout(t *testing.T) {
+	m := newTestModel(t, `{"a":1}`)
+	m.flash("one")
+	m.flash("two")
+	// the timeout of the first message arrives after the second was shown
+	m = send(m, clearStatusMsg{id: m.statusID - 1})
+	if m.status != "two" {
+		t.Errorf("a stale timeout cleared %q", m.status)
+	}
+	if m = send(m, clearStatusMsg{id: m.statusID}); m.status != "" {
+		t.Errorf("status %q was not cleared", m.status)
+	}
+}
+
+func TestCopyToClipboardWrapping(t *testing.T) {
+	tests := []struct {
+		tmux, term string
+		prefix     string
+	}{
+		{"", "xterm", "\x1b]52;c;"},
+		{"/tmp/tmux-1000/default,1,0", "screen", "\x1bPtmux;"},
+		{"", "screen-256color", "\x1bP\x1b]52;c;"},
+	}
+	for _, tt := range tests {
+		t.Setenv("TMUX", tt.tmux)
+		t.Setenv("TERM", tt.term)
+		raw := copyToClipboard("x")().(tea.RawMsg).Msg.(string)
+		if !strings.HasPrefix(raw, tt.prefix) {
+			t.Errorf("TMUX=%q TERM=%q: sequence %q, want prefix %q", tt.tmux, tt.term, raw, tt.prefix)
+		}
+	}
+}
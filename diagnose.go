@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// frameContext is the number of lines shown before the error line in a code frame
+const frameContext = 2
+
+// frameWidth is the maximum width of a line in a code frame, longer lines are cut around the error
+const frameWidth = 80
+
+// parseError is a JSON syntax error located in the input
+type parseError struct {
+	offset    int    // byte offset of the offending character
+	line, col int    // 1-based, col counts characters
+	msg       string // message of the JSON decoder
+	frame     string // the lines around the error with a caret under it
+	hint      string // likely cause, empty when none is known
+}
+
+func (e *parseError) Error() string {
+	s := fmt.Sprintf("invalid JSON at line %d, column %d (byte offset %d): %s\n%s", e.line, e.col, e.offset, e.msg, e.frame)
+	if e.hint != "" {
+		s += "\nhint: " + e.hint
+	}
+	return s
+}
+
+// syntaxError describes why data is not a valid JSON document
+func syntaxError(data []byte) error {
+	var v json.RawMessage
+	err := json.Unmarshal(data, &v)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return newParseError(data, syntaxErr.Offset, syntaxErr.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return errors.New("invalid JSON")
+}
+
+// newParseError locates a decoder error, offset is the number of bytes read when it occurred
+func newParseError(data []byte, offset int64, msg string) *parseError {
+	pos := int(offset)
+	if pos > 0 && pos <= len(data) && !strings.HasPrefix(msg, "unexpected end") {
+		// the decoder has read the offending character already
+		pos--
+	}
+	pos = min(pos, len(data))
+	return newParseErrorAt(data, pos, msg, syntaxHint(data, pos))
+}
+
+// newParseErrorAt locates an error at the byte offset pos
+func newParseErrorAt(data []byte, pos int, msg, hint string) *parseError {
+	line, col := lineCol(data, pos)
+	return &parseError{
+		offset: pos,
+		line:   line,
+		col:    col,
+		msg:    msg,
+		frame:  codeFrame(data, pos, line, col),
+		hint:   hint,
+	}
+}
+
+// lineCol returns the 1-based line and column of a byte offset, the column in characters
+func lineCol(data []byte, offset int) (line, col int) {
+	offset = min(offset, len(data))
+	start := bytes.LastIndexByte(data[:offset], '\n') + 1
+	return bytes.Count(data[:offset], []byte("\n")) + 1, utf8.RuneCount(data[start:offset]) + 1
+}
+
+// codeFrame shows the error line and the lines before it, with a caret under the error
+func codeFrame(data []byte, offset, line, col int) string {
+	lines := strings.Split(string(data[:offset]), "\n")
+	if end := bytes.IndexByte(data[offset:], '\n'); end >= 0 {
+		lines[len(lines)-1] += string(data[offset : offset+end])
+	} else {
+		lines[len(lines)-1] += string(data[offset:])
+	}
+	first := max(len(lines)-1-frameContext, 0)
+	gutter := len(fmt.Sprint(line))
+
+	// long lines, e.g. minified JSON, are cut to a window around the error
+	skip := max(col-1-frameWidth/2, 0)
+	var b strings.Builder
+	for i := first; i < len(lines); i++ {
+		text := []rune(strings.TrimRight(lines[i], "\r"))
+		text = text[min(skip, len(text)):]
+		if len(text) > frameWidth {
+			text = append(text[:frameWidth-1], '…')
+		}
+		prefix := ""
+		if skip > 0 && len(text) > 0 {
+			prefix = "…"
+		}
+		fmt.Fprintf(&b, "%*d | %s%s\n", gutter, line-len(lines)+1+i, prefix, strings.ReplaceAll(string(text), "\t", " "))
+	}
+	caret := col - 1 - skip
+	if skip > 0 {
+		caret++
+	}
+	fmt.Fprintf(&b, "%s | %s^", strings.Repeat(" ", gutter), strings.Repeat(" ", caret))
+	return b.String()
+}
+
+// syntaxHint guesses the mistake behind an error at offset from the text around it
+func syntaxHint(data []byte, offset int) string {
+	rest := data[offset:]
+	before := bytes.TrimRight(data[:offset], " \t\r\n")
+	var prev byte
+	if len(before) > 0 {
+		prev = before[len(before)-1]
+	}
+	switch {
+	case len(bytes.TrimSpace(rest)) == 0:
+		return "the input ends before all objects, arrays and strings are closed"
+	case (rest[0] == '}' || rest[0] == ']') && prev == ',':
+		return "trailing commas are not allowed, remove the comma before '" + string(rest[0]) + "'"
+	case rest[0] < ' ':
+		return "line breaks and control characters must be escaped inside strings, or a string is not closed"
+	case rest[0] == '\'':
+		return "strings and keys must be quoted with double quotes, not single quotes"
+	case hasWordPrefix(rest, "NaN"), hasWordPrefix(rest, "Infinity"), hasWordPrefix(rest, "-Infinity"):
+		return "NaN and Infinity are not JSON numbers, use null or a string instead"
+	case hasWordPrefix(rest, "undefined"):
+		return "undefined is not a JSON value, use null instead"
+	case rest[0] == '/' && len(rest) > 1 && (rest[1] == '/' || rest[1] == '*'):
+		return "comments are not allowed in JSON, JSONC / JSON5 is read with -jsonc"
+	case isIdentStart(rest[0]) && (prev == '{' || prev == ','):
+		return "object keys must be quoted with double quotes"
+	case isIdentStart(rest[0]) && (prev == ':' || prev == '['):
+		return "strings must be quoted with double quotes"
+	case rest[0] == '"' && (prev == '"' || prev == '}' || prev == ']' || isIdentPart(prev)):
+		return "a comma is missing before this value"
+	case (rest[0] == '{' || rest[0] == '[') && (prev == '}' || prev == ']'):
+		return "a comma is missing between the values, or the input holds several documents (JSON Lines are read with -ndjson)"
+	}
+	return ""
+}
+
+// hasWordPrefix reports whether data starts with word followed by a character that cannot continue it
+func hasWordPrefix(data []byte, word string) bool {
+	return bytes.HasPrefix(data, []byte(word)) && (len(data) == len(word) || !isIdentPart(data[len(word)]))
+}
+
+// isIdentStart reports whether c can start an unquoted key
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= utf8.RuneSelf
+}
+
+// isIdentPart reports whether c can continue an unquoted key
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSyntaxHint(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{"a": [1, 2`, "the input ends before all objects, arrays and strings are closed"},
+		{`{"a": 1,}`, "trailing commas are not allowed, remove the comma before '}'"},
+		{`[1, 2,]`, "trailing commas are not allowed, remove the comma before ']'"},
+		{"{\"a\": \"line\nbreak\"}", "line breaks and control characters must be escaped inside strings, or a string is not closed"},
+		{`{'a': 1}`, "strings and keys must be quoted with double quotes, not single quotes"},
+		{`{"a": NaN}`, "NaN and Infinity are not JSON numbers, use null or a string instead"},
+		{`[-Infinity]`, "NaN and Infinity are not JSON numbers, use null or a string instead"},
+		{`{"a": undefined}`, "undefined is not a JSON value, use null instead"},
+		{"{\n  // note\n  \"a\": 1\n}", "comments are not allowed in JSON, JSONC / JSON5 is read with -jsonc"},
+		{`{a: 1}`, "object keys must be quoted with double quotes"},
+		{`{"a": 1, b: 2}`, "object keys must be quoted with double quotes"},
+		{`{"a": yes}`, "strings must be quoted with double quotes"},
+		{`{"a": "x" "b": 1}`, "a comma is missing before this value"},
+		{"{\"a\": 1}\n{\"a\": 2}", "a comma is missing between the values, or the input holds several documents (JSON Lines are read with -ndjson)"},
+		{`{"a": 1:}`, ""},
+	}
+	for _, tt := range tests {
+		var perr *parseError
+		if !errors.As(syntaxError([]byte(tt.in)), &perr) {
+			t.Errorf("syntaxError(%q) is not a parse error", tt.in)
+			continue
+		}
+		if perr.hint != tt.want {
+			t.Errorf("hint for %q = %q, want %q", tt.in, perr.hint, tt.want)
+		}
+	}
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	var perr *parseError
+	if !errors.As(syntaxError([]byte("{\n  \"ü\": 1,\n  \"b\": x\n}")), &perr) {
+		t.Fatal("not a parse error")
+	}
+	if perr.line != 3 || perr.col != 8 || perr.offset != 20 {
+		t.Errorf("error at line %d, column %d, offset %d, want line 3, column 8, offset 20", perr.line, perr.col, perr.offset)
+	}
+}
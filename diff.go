@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tidwall/gjson"
+)
+
+// diffColumnGap is the space between the old and new columns of the extractor in diff mode
+const diffColumnGap = 2
+
+// diffStatus marks how a node differs between the two documents of diff mode
+type diffStatus int
+
+const (
+	diffNone      diffStatus = iota // not in diff mode
+	diffUnchanged                   // same value in both documents
+	diffChanged                     // present in both with a different value
+	diffAdded                       // only in the new document
+	diffRemoved                     // only in the old document
+)
+
+// marker returns the glyph shown in front of a node name
+func (s diffStatus) marker() string {
+	switch s {
+	case diffUnchanged:
+		return "  "
+	case diffChanged:
+		return "~ "
+	case diffAdded:
+		return "+ "
+	case diffRemoved:
+		return "- "
+	}
+	return ""
+}
+
+// style returns the style of the marker
+func (s diffStatus) style() lipgloss.Style {
+	switch s {
+	case diffChanged:
+		return lipgloss.NewStyle().Foreground(tnYellow)
+	case diffAdded:
+		return lipgloss.NewStyle().Foreground(tnGreen)
+	case diffRemoved:
+		return lipgloss.NewStyle().Foreground(tnRed)
+	}
+	return lipgloss.NewStyle()
+}
+
+// diffView holds the two documents compared in diff mode
+type diffView struct {
+	old, new         *JSONProcessor
+	oldName, newName string
+	counts           map[diffStatus]int
+}
+
+// newDiffView prepares two loaded documents for comparison
+func newDiffView(old, new *JSONProcessor, oldName, newName string) *diffView {
+	return &diffView{
+		old:     diffDocument(old),
+		new:     diffDocument(new),
+		oldName: oldName,
+		newName: newName,
+		counts:  make(map[diffStatus]int),
+	}
+}
+
+// diffDocument returns the document compared in diff mode
+// JSON Lines records and YAML documents are compared as an array of records
+func diffDocument(jp *JSONProcessor) *JSONProcessor {
+	if len(jp.records) == 0 {
+		return jp
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, record := range jp.records {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(record.jsonData)
+	}
+	buf.WriteByte(']')
+	return &JSONProcessor{jsonData: buf.Bytes(), format: jp.format}
+}
+
+// buildTree builds the merged tree of both documents and counts the changed leaves
+func (d *diffView) buildTree() *TreeNode {
+	root := &TreeNode{depth: -1, expanded: true}
+	diffChildren(root, nil, gjson.ParseBytes(d.old.jsonData), gjson.ParseBytes(d.new.jsonData))
+
+	clear(d.counts)
+	walkTree(root, func(n *TreeNode) {
+		if !n.hasChildren() && n.name != "#" {
+			d.counts[n.status]++
+		}
+	})
+	return root
+}
+
+// summary describes the number of added, removed and changed leaves
+func (d *diffView) summary() string {
+	return fmt.Sprintf("%d added · %d removed · %d changed",
+		d.counts[diffAdded], d.counts[diffRemoved], d.counts[diffChanged])
+}
+
+// diffNode adds the merged node of old and new below parent and returns its status
+func diffNode(parent *TreeNode, path jsonPath, name string, old, new gjson.Result) diffStatus {
+	node := &TreeNode{
+		path:     path,
+		key:      path.String(),
+		name:     name,
+		depth:    parent.depth + 1,
+		parent:   parent,
+		expanded: true,
+	}
+	parent.children = append(parent.children, node)
+	node.status = diffChildren(node, path, old, new)
+	return node.status
+}
+
+// diffChildren adds the merged children of old and new to node and returns the status of the pair
+// object members keep the order of the new document with removed members at their old position,
+// array elements are compared by index
+func diffChildren(node *TreeNode, path jsonPath, old, new gjson.Result) diffStatus {
+	changed := false
+	if old.IsObject() || new.IsObject() {
+		oldMembers, oldOrder := objectMembers(old)
+		newMembers, newOrder := objectMembers(new)
+		for _, key := range mergeKeys(oldOrder, newOrder, newMembers) {
+			status := diffNode(node, path.child(key), key, oldMembers[key], newMembers[key])
+			changed = changed || status != diffUnchanged
+		}
+	}
+	if old.IsArray() || new.IsArray() {
+		oldElems, newElems := arrayElements(old), arrayElements(new)
+		var oldCount, newCount gjson.Result
+		if old.IsArray() {
+			oldCount = gjson.Parse(fmt.Sprint(len(oldElems)))
+		}
+		if new.IsArray() {
+			newCount = gjson.Parse(fmt.Sprint(len(newElems)))
+		}
+		diffNode(node, path.count(), "#", oldCount, newCount)
+		for i := range max(len(oldElems), len(newElems)) {
+			var oldElem, newElem gjson.Result
+			if i < len(oldElems) {
+				oldElem = oldElems[i]
+			}
+			if i < len(newElems) {
+				newElem = newElems[i]
+			}
+			status := diffNode(node, path.element(i), fmt.Sprintf("[%d]", i), oldElem, newElem)
+			changed = changed || status != diffUnchanged
+		}
+	}
+
+	if new.Exists() {
+		node.describe(new)
+	} else {
+		node.describe(old)
+	}
+	if !isContainer(old) && old.Exists() {
+		node.values = append(node.values, old.String())
+	}
+	if !isContainer(new) && new.Exists() && (!old.Exists() || new.String() != old.String()) {
+		node.values = append(node.values, new.String())
+	}
+
+	switch {
+	case !old.Exists():
+		return diffAdded
+	case !new.Exists():
+		return diffRemoved
+	case changed || old.IsObject() != new.IsObject() || old.IsArray() != new.IsArray():
+		return diffChanged
+	case !isContainer(old) && !sameScalar(old, new):
+		return diffChanged
+	}
+	return diffUnchanged
+}
+
+// isContainer reports whether the value is an object or an array
+func isContainer(v gjson.Result) bool {
+	return v.IsObject() || v.IsArray()
+}
+
+// sameScalar compares two scalars, numbers are compared by value so that 1 and 1.0 are equal
+func sameScalar(a, b gjson.Result) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	if a.Type == gjson.Number {
+		return a.Raw == b.Raw || a.Num == b.Num
+	}
+	return a.String() == b.String()
+}
+
+// objectMembers indexes the members of an object and returns their keys in document order
+func objectMembers(v gjson.Result) (map[string]gjson.Result, []string) {
+	members := make(map[string]gjson.Result)
+	var order []string
+	if !v.IsObject() {
+		return members, order
+	}
+	v.ForEach(func(key, val gjson.Result) bool {
+		if _, dup := members[key.String()]; !dup {
+			members[key.String()] = val
+			order = append(order, key.String())
+		}
+		return true
+	})
+	return members, order
+}
+
+// mergeKeys lists the keys of the new object and inserts removed keys after their old predecessor
+func mergeKeys(oldOrder, newOrder []string, newMembers map[string]gjson.Result) []string {
+	// removed keys without a kept predecessor come first
+	var leading []string
+	removedAfter := make(map[string][]string)
+	prev, hasPrev := "", false
+	for _, key := range oldOrder {
+		if _, kept := newMembers[key]; kept {
+			prev, hasPrev = key, true
+		} else if hasPrev {
+			removedAfter[prev] = append(removedAfter[prev], key)
+		} else {
+			leading = append(leading, key)
+		}
+	}
+
+	merged := leading
+	for _, key := range newOrder {
+		merged = append(merged, key)
+		merged = append(merged, removedAfter[key]...)
+	}
+	return merged
+}
+
+// arrayElements returns the elements of an array, or nil for other values
+func arrayElements(v gjson.Result) []gjson.Result {
+	if !v.IsArray() {
+		return nil
+	}
+	return v.Array()
+}
+
+// diffContent renders the old and new value of a key side by side
+func (m *Model) diffContent(key string) string {
+	width := max((m.extractViewport.Width()-diffColumnGap)/2, 10)
+	column := func(jp *JSONProcessor, title string, status diffStatus) string {
+		value := "(absent)"
+		if _, ok := queryResults(key, jp.jsonData); ok {
+			if m.yamlOutput {
+				value = highlightYAML(jp.toYAML(key))
+			} else {
+				value = highlightJSON(getParsedResult(key, jp.jsonData))
+			}
+		}
+		header := status.style().Bold(true).Render(title)
+		return lipgloss.NewStyle().Width(width).Render(header + "\n" + value)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		column(m.diff.old, "--- "+m.diff.oldName, diffRemoved),
+		strings.Repeat(" ", diffColumnGap),
+		column(m.diff.new, "+++ "+m.diff.newName, diffAdded),
+	)
+}
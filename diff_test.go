@@ -0,0 +1,115 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/tidwall/gjson"
+)
+
+func TestMergeKeys(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     []string
+	}{
+		{"a b c", "a b c", []string{"a", "b", "c"}},
+		// reordered keys follow the new document
+		{"a b c", "c a b", []string{"c", "a", "b"}},
+		// removed keys stay after their old predecessor
+		{"a b c d", "a d", []string{"a", "b", "c", "d"}},
+		{"a b c", "c a", []string{"c", "a", "b"}},
+		// removed keys without a kept predecessor come first
+		{"x a", "a y", []string{"x", "a", "y"}},
+		{"x y", "", []string{"x", "y"}},
+		{"", "a b", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		oldOrder, newOrder := strings.Fields(tt.old), strings.Fields(tt.new)
+		newMembers := make(map[string]gjson.Result)
+		for _, key := range newOrder {
+			newMembers[key] = gjson.Parse("1")
+		}
+		if got := mergeKeys(oldOrder, newOrder, newMembers); !slices.Equal(got, tt.want) {
+			t.Errorf("mergeKeys(%q, %q) = %v, want %v", tt.old, tt.new, got, tt.want)
+		}
+	}
+}
+
+func TestDiffChildren(t *testing.T) {
+	old := `{"name":"web","replicas":2,"ports":[80,443],"removed":true,"same":{"x":1.0},"kind":{"a":1}}`
+	new := `{"name":"web","replicas":3,"ports":[80],"added":null,"same":{"x":1},"kind":[1]}`
+	root := &TreeNode{depth: -1}
+	status := diffChildren(root, nil, gjson.Parse(old), gjson.Parse(new))
+	if status != diffChanged {
+		t.Errorf("document status = %d, want changed", status)
+	}
+
+	want := map[string]diffStatus{
+		"name":     diffUnchanged,
+		"replicas": diffChanged,
+		"ports":    diffChanged,
+		"ports.#":  diffChanged,
+		"ports[0]": diffUnchanged,
+		"ports[1]": diffRemoved,
+		"removed":  diffRemoved,
+		"added":    diffAdded,
+		"same":     diffUnchanged,
+		"same.x":   diffUnchanged, // numbers are compared by value
+		"kind":     diffChanged,   // an object became an array
+		"kind.a":   diffRemoved,
+		"kind.#":   diffAdded,
+		"kind[0]":  diffAdded,
+	}
+	got := make(map[string]diffStatus)
+	var order []string
+	walkTree(root, func(n *TreeNode) {
+		got[n.key] = n.status
+		if n.depth == 0 {
+			order = append(order, n.key)
+		}
+	})
+	for key, status := range want {
+		if got[key] != status {
+			t.Errorf("%s: status %d, want %d", key, got[key], status)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("merged tree has %d nodes, want %d", len(got), len(want))
+	}
+	wantOrder := []string{"name", "replicas", "ports", "removed", "added", "same", "kind"}
+	if !slices.Equal(order, wantOrder) {
+		t.Errorf("members = %v, want %v", order, wantOrder)
+	}
+}
+
+func TestDiffModel(t *testing.T) {
+	old := &JSONProcessor{jsonData: []byte(`{"name":"web","replicas":2,"debug":true}`)}
+	new := &JSONProcessor{jsonData: []byte(`{"name":"web","replicas":3,"owner":"ops"}`)}
+	m := send(NewDiffModel(old, new, "old.json", "new.json"), tea.WindowSizeMsg{Width: 120, Height: 30})
+
+	if got, want := rowKeys(m), []string{"name", "replicas", "debug", "owner"}; !slices.Equal(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+	if header := m.renderHeader(); !strings.Contains(header, "1 added · 1 removed · 1 changed") {
+		t.Errorf("header = %q", header)
+	}
+	markers := make(map[string]string)
+	for _, row := range m.rows {
+		markers[row.key] = m.formatTreeItemPlain(row, false)
+	}
+	want := map[string]string{"name": "   name", "replicas": "~ replicas", "debug": "- debug", "owner": "+ owner"}
+	for key, row := range want {
+		if !strings.Contains(markers[key], row) {
+			t.Errorf("row of %s = %q, want it to contain %q", key, markers[key], row)
+		}
+	}
+
+	// the extractor shows the old and new value side by side, absent ones marked
+	m = send(m, tea.KeyPressMsg{Code: tea.KeyDown}, tea.KeyPressMsg{Code: tea.KeyDown})
+	content := m.diffContent(m.rows[m.selectedIdx].key)
+	if !strings.Contains(content, "old.json") || !strings.Contains(content, "new.json") || !strings.Contains(content, "(absent)") {
+		t.Errorf("diff content of %s = %q", m.rows[m.selectedIdx].key, content)
+	}
+}
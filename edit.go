@@ -0,0 +1,533 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// quitWarning is shown when quitting with unsaved edits, quitting again discards them
+const quitWarning = "unsaved changes, press again to quit"
+
+// editOp is the change an edit prompt applies when it is submitted
+type editOp int
+
+const (
+	editValue    editOp = iota // replace the value of the selected node
+	editRename                 // rename the selected object member
+	editAddKey                 // name of a new object member, followed by editAddValue
+	editAddValue               // value of the new object member
+	editAppend                 // value appended to an array
+	editSaveAs                 // path the input read from stdin is saved to
+	editFind                   // text searched for in the extractor
+	editJump                   // index of the array element to select
+	editExport                 // file the selection is exported to
+	editLine                   // line of the input to select the node of
+)
+
+// editPrompt is the input shown in the search bar while editing
+type editPrompt struct {
+	op   editOp
+	path jsonPath // node, object or array the edit applies to
+	key  string   // member name entered in the editAddKey step
+	text string
+
+	// prevCursor restores the search bar cursor when the prompt closes
+	prevCursor int
+}
+
+// label returns the prompt shown in front of the input
+func (p *editPrompt) label() string {
+	switch p.op {
+	case editRename:
+		return "Rename: "
+	case editAddKey:
+		return "New key: "
+	case editAddValue:
+		return fmt.Sprintf("Value of %s: ", p.key)
+	case editAppend:
+		return "Append: "
+	case editSaveAs:
+		return "Save as: "
+	case editFind:
+		return "Find: "
+	case editJump:
+		return "Go to index: "
+	case editExport:
+		return "Export to: "
+	case editLine:
+		return "Go to line: "
+	default:
+		return "Edit: "
+	}
+}
+
+// documentStyle is the layout of an edited document, restored after every change
+type documentStyle struct {
+	indent    string // indentation unit, empty for single-line documents
+	canonical bool   // the document is laid out exactly as json.Indent would lay it out
+	newline   bool   // the document ends with a newline
+}
+
+// detectStyle detects the indentation of a document
+// only documents written by a formatter are re-indented, others keep their layout and
+// get new members inserted compactly
+func detectStyle(data []byte) documentStyle {
+	trimmed := bytes.TrimSpace(data)
+	style := documentStyle{newline: bytes.HasSuffix(data, []byte("\n"))}
+	if i := bytes.IndexByte(trimmed, '\n'); i >= 0 {
+		line := trimmed[i+1:]
+		style.indent = string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, trimmed); err != nil {
+		return style
+	}
+	formatted := compact.Bytes()
+	if style.indent != "" {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, formatted, "", style.indent); err != nil {
+			return style
+		}
+		formatted = indented.Bytes()
+	}
+	style.canonical = bytes.Equal(formatted, trimmed)
+	return style
+}
+
+// apply lays out an edited document in the style
+func (s documentStyle) apply(data []byte) []byte {
+	data = bytes.TrimSpace(data)
+	if s.canonical {
+		var buf bytes.Buffer
+		err := json.Compact(&buf, data)
+		if err == nil && s.indent != "" {
+			compact := bytes.Clone(buf.Bytes())
+			buf.Reset()
+			err = json.Indent(&buf, compact, "", s.indent)
+		}
+		if err == nil {
+			data = buf.Bytes()
+		}
+	}
+	if s.newline {
+		data = append(bytes.Clone(data), '\n')
+	}
+	return data
+}
+
+// parseEditValue reads typed input as a JSON value, text that is not valid JSON becomes a string
+func parseEditValue(text string) []byte {
+	if trimmed := strings.TrimSpace(text); trimmed != "" && gjson.Valid(trimmed) {
+		return []byte(trimmed)
+	}
+	raw, _ := json.Marshal(text)
+	return raw
+}
+
+// lookup returns the value at path, the whole document for the empty path
+func lookup(data []byte, path jsonPath) gjson.Result {
+	if len(path) == 0 {
+		return gjson.ParseBytes(data)
+	}
+	return gjson.GetBytes(data, path.gjson())
+}
+
+// setValue replaces the value at path, or creates it when it does not exist
+func setValue(data []byte, path jsonPath, raw []byte) ([]byte, error) {
+	if len(path) == 0 {
+		return raw, nil
+	}
+	return sjson.SetRawBytes(data, path.gjson(), raw)
+}
+
+// deleteValue removes the value at path from its object or array
+func deleteValue(data []byte, path jsonPath) ([]byte, error) {
+	return sjson.DeleteBytes(data, path.gjson())
+}
+
+// appendValue appends an element to the array at path
+func appendValue(data []byte, path jsonPath, raw []byte) ([]byte, error) {
+	if len(path) == 0 {
+		return sjson.SetRawBytes(data, "-1", raw)
+	}
+	return sjson.SetRawBytes(data, path.gjson()+".-1", raw)
+}
+
+// renameMember replaces the name of the object member at path, keeping its position
+func renameMember(data []byte, path jsonPath, name string) ([]byte, error) {
+	value := gjson.GetBytes(data, path.gjson())
+	if !value.Exists() || value.Index == 0 {
+		return nil, fmt.Errorf("%s not found", path)
+	}
+
+	// walk back from the value over the colon to the quotes around the member name
+	i := value.Index - 1
+	for i >= 0 && isJSONSpace(data[i]) {
+		i--
+	}
+	if i < 0 || data[i] != ':' {
+		return nil, fmt.Errorf("%s is not an object member", path)
+	}
+	for i--; i >= 0 && isJSONSpace(data[i]); i-- {
+	}
+	if i < 0 || data[i] != '"' {
+		return nil, fmt.Errorf("%s is not an object member", path)
+	}
+	end := i
+	for i--; i >= 0; i-- {
+		if data[i] == '"' && !isEscaped(data, i) {
+			break
+		}
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("%s is not an object member", path)
+	}
+
+	quoted, err := json.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(data)+len(quoted))
+	out = append(out, data[:i]...)
+	out = append(out, quoted...)
+	return append(out, data[end+1:]...), nil
+}
+
+// isJSONSpace reports whether c is insignificant whitespace in JSON
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isEscaped reports whether the byte at i is preceded by an odd number of backslashes
+func isEscaped(data []byte, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && data[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// writeFile replaces the file at path through a temporary file, keeping the mode of an existing file
+// the input may still be memory-mapped, so it is never truncated in place
+func writeFile(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// selectedPath returns the path of the selected node
+func (m *Model) selectedPath() (jsonPath, bool) {
+	if m.selectedIdx < 0 || m.selectedIdx >= len(m.rows) {
+		return nil, false
+	}
+	return m.rows[m.selectedIdx].path, true
+}
+
+// editable reports why the current view cannot be edited, or nil when it can
+func (m *Model) editable() error {
+	switch {
+	case m.diff != nil:
+		return errors.New("editing is not available in diff mode")
+	case m.jp.format == formatYAML:
+		return errors.New("editing YAML input is not supported")
+	case m.jp.format == formatJSON5:
+		return errors.New("editing JSONC / JSON5 input is not supported, saving would drop its comments")
+	case m.recordView != viewRecord:
+		return errors.New("open a record to edit it")
+	}
+	return nil
+}
+
+// startEdit opens the edit prompt for op on the selected node
+func (m *Model) startEdit(op editOp) {
+	if err := m.editable(); err != nil {
+		m.status = err.Error()
+		return
+	}
+	path, ok := m.selectedPath()
+	if !ok && op != editAddKey {
+		m.status = "nothing selected"
+		return
+	}
+	if path.hasEach() {
+		m.status = "open the range and select an element to edit"
+		return
+	}
+	data := m.source().jsonData
+	last := pathSegment{kind: segmentKey}
+	if len(path) > 0 {
+		last = path[len(path)-1]
+	}
+
+	prompt := &editPrompt{op: op, path: path, prevCursor: m.searchCursor}
+	switch op {
+	case editValue:
+		if last.kind == segmentCount {
+			m.status = "the array length cannot be edited"
+			return
+		}
+		var compact bytes.Buffer
+		raw := lookup(data, path).Raw
+		if json.Compact(&compact, []byte(raw)) == nil {
+			raw = compact.String()
+		}
+		prompt.text = raw
+	case editRename:
+		if len(path) == 0 || last.kind != segmentKey {
+			m.status = "only object members can be renamed"
+			return
+		}
+		prompt.text = last.key
+	case editAddKey:
+		// leaves add a sibling, containers a child
+		if !isContainer(lookup(data, path)) && len(path) > 0 {
+			prompt.path = path[:len(path)-1]
+		}
+		if lookup(data, prompt.path).IsArray() {
+			prompt.op = editAppend
+		} else if !lookup(data, prompt.path).IsObject() {
+			m.status = "values can only be added to objects and arrays"
+			return
+		}
+	}
+	m.edit = prompt
+	m.searchCursor = len(prompt.text)
+}
+
+// closeEdit closes the edit prompt and restores the search bar
+func (m *Model) closeEdit() {
+	if m.edit != nil {
+		m.searchCursor = m.edit.prevCursor
+		m.edit = nil
+	}
+}
+
+// submitEdit applies the edit prompt
+func (m *Model) submitEdit() {
+	p := m.edit
+	m.closeEdit()
+
+	var err error
+	switch p.op {
+	case editValue:
+		err = m.applyEdit(p.path, func(data []byte) ([]byte, error) {
+			return setValue(data, p.path, parseEditValue(p.text))
+		})
+	case editRename:
+		parent, name := p.path[:len(p.path)-1], p.text
+		if last := p.path[len(p.path)-1]; last.kind == segmentKey && last.key == name {
+			// renaming a member to its current name changes nothing
+			break
+		}
+		if lookup(m.source().jsonData, parent.child(name)).Exists() {
+			err = fmt.Errorf("key %q already exists", name)
+			break
+		}
+		err = m.applyEdit(parent.child(name), func(data []byte) ([]byte, error) {
+			return renameMember(data, p.path, name)
+		})
+	case editAddKey:
+		if lookup(m.source().jsonData, p.path.child(p.text)).Exists() {
+			err = fmt.Errorf("key %q already exists", p.text)
+			break
+		}
+		m.edit = &editPrompt{op: editAddValue, path: p.path, key: p.text, text: "null", prevCursor: p.prevCursor}
+		m.searchCursor = len(m.edit.text)
+	case editAddValue:
+		err = m.applyEdit(p.path.child(p.key), func(data []byte) ([]byte, error) {
+			return setValue(data, p.path.child(p.key), parseEditValue(p.text))
+		})
+	case editAppend:
+		index := len(lookup(m.source().jsonData, p.path).Array())
+		err = m.applyEdit(p.path.element(index), func(data []byte) ([]byte, error) {
+			return appendValue(data, p.path, parseEditValue(p.text))
+		})
+	case editSaveAs:
+		err = m.save(strings.TrimSpace(p.text))
+	case editFind:
+		// the find query is applied while typing
+	case editJump:
+		err = m.jumpToIndex(p.path, p.text)
+	case editExport:
+		err = m.exportSelection(strings.TrimSpace(p.text))
+	case editLine:
+		err = m.gotoLine(p.text)
+	}
+	if err != nil {
+		m.status = err.Error()
+	}
+}
+
+// deleteSelected removes the selected node from its object or array
+func (m *Model) deleteSelected() {
+	if err := m.editable(); err != nil {
+		m.status = err.Error()
+		return
+	}
+	path, ok := m.selectedPath()
+	if !ok || len(path) == 0 || path[len(path)-1].kind == segmentCount || path.hasEach() {
+		m.status = "nothing to delete"
+		return
+	}
+	err := m.applyEdit(path[:len(path)-1], func(data []byte) ([]byte, error) {
+		return deleteValue(data, path)
+	})
+	if err != nil {
+		m.status = err.Error()
+	}
+}
+
+// applyEdit changes the document of the current view, refreshes the key index and the tree,
+// and selects the node at selectPath
+func (m *Model) applyEdit(selectPath jsonPath, change func(data []byte) ([]byte, error)) error {
+	doc := m.source()
+	if doc.style == nil {
+		style := detectStyle(doc.jsonData)
+		doc.style = &style
+	}
+	data, err := change(doc.jsonData)
+	if err != nil {
+		return err
+	}
+	if !gjson.ValidBytes(data) {
+		return errors.New("the edit would produce invalid JSON")
+	}
+	doc.jsonData = doc.style.apply(data)
+	doc.doc, doc.docErr, doc.decoded = nil, nil, false
+
+	// offsets now point into the edited document, a recovered input is no longer shown
+	m.jp.source, m.jp.sourceMap, m.jp.lines = nil, nil, nil
+	if doc != m.jp {
+		// JSON Lines records are written back one per line, records spanning several lines
+		// are compacted so that the stream stays valid
+		var stream bytes.Buffer
+		for _, record := range m.jp.records {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, record.jsonData); err != nil {
+				return err
+			}
+			if !bytes.Equal(compact.Bytes(), record.jsonData) {
+				record.jsonData = compact.Bytes()
+				record.doc, record.docErr, record.decoded = nil, nil, false
+			}
+			record.base = stream.Len()
+			stream.Write(record.jsonData)
+			stream.WriteByte('\n')
+		}
+		m.jp.jsonData = stream.Bytes()
+		m.jp.doc, m.jp.docErr, m.jp.decoded = nil, nil, false
+	}
+	m.jp.extractKeys()
+	if m.jp.schema != nil {
+		if err := doc.validate(m.jp.schema); err != nil {
+			return err
+		}
+		m.violationIdx = 0
+	}
+	m.dirty = true
+	m.refreshTree(selectPath)
+	return nil
+}
+
+// refreshTree rebuilds the tree after an edit, keeping folded nodes folded
+func (m *Model) refreshTree(selectPath jsonPath) {
+	collapsed := make(map[string]struct{})
+	opened := make(map[string]struct{})
+	walkTree(m.root, func(n *TreeNode) {
+		if n.hasChildren() && !n.expanded {
+			collapsed[n.key] = struct{}{}
+		}
+		if n.expanded && n.path.hasEach() {
+			opened[n.key] = struct{}{}
+		}
+	})
+
+	m.root = m.buildViewTree()
+	m.table = nil
+	walkTree(m.root, func(n *TreeNode) {
+		if _, ok := collapsed[n.key]; ok {
+			n.expanded = false
+		}
+		// buckets load before walkTree descends into them
+		if _, ok := opened[n.key]; ok {
+			n.loadBucket()
+			n.expanded = true
+		}
+	})
+
+	m.rows = nil
+	if m.filterRoot == nil {
+		m.updateRows()
+	} else {
+		m.updateFilteredKeys()
+	}
+	if target := m.root.locate(selectPath); target != nil {
+		m.showNode(target)
+	}
+	if m.searchMode == searchJQ {
+		m.updateQueryResult()
+	}
+}
+
+// saveInput writes the edits back to the input file, input read from stdin asks for a path
+func (m *Model) saveInput() {
+	if m.diff != nil {
+		m.status = "editing is not available in diff mode"
+		return
+	}
+	if m.jp.path == "" {
+		m.edit = &editPrompt{op: editSaveAs, prevCursor: m.searchCursor}
+		m.searchCursor = 0
+		return
+	}
+	if err := m.save(m.jp.path); err != nil {
+		m.status = err.Error()
+	}
+}
+
+// save writes the input to path
+func (m *Model) save(path string) error {
+	if path == "" {
+		return errors.New("no file name given")
+	}
+	if !m.dirty && path == m.jp.path {
+		m.status = "no changes to save"
+		return nil
+	}
+	if err := writeFile(path, m.jp.jsonData); err != nil {
+		return fmt.Errorf("saving %s: %w", path, err)
+	}
+	m.jp.path = path
+	m.fileName = path
+	m.dirty = false
+	m.status = "saved " + path
+	return nil
+}
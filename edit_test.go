@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func TestDetectStyle(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want documentStyle
+	}{
+		{"compact", `{"a":1,"b":[1,2]}`, documentStyle{canonical: true}},
+		{"compact with newline", "{\"a\":1}\n", documentStyle{canonical: true, newline: true}},
+		{"two spaces", "{\n  \"a\": 1,\n  \"b\": [\n    1\n  ]\n}\n", documentStyle{indent: "  ", canonical: true, newline: true}},
+		{"tabs", "{\n\t\"a\": 1\n}", documentStyle{indent: "\t", canonical: true}},
+		{"hand written", "{\n  \"a\": 1, \"b\": 2\n}", documentStyle{indent: "  "}},
+		{"spaces in a single line", `{"a": 1}`, documentStyle{}},
+	}
+	for _, tt := range tests {
+		if got := detectStyle([]byte(tt.data)); got != tt.want {
+			t.Errorf("%s: detectStyle = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDocumentStyleApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		edited   string
+		want     string
+	}{
+		{"indented", "{\n  \"a\": 1\n}\n", `{"a": 1,"b":[2]}`, "{\n  \"a\": 1,\n  \"b\": [\n    2\n  ]\n}\n"},
+		{"compact", `{"a":1}`, `{"a":1, "b":2}`, `{"a":1,"b":2}`},
+		{"hand written keeps its layout", "{\n  \"a\": 1, \"b\": 2\n}", "{\n  \"a\": 1, \"b\": 2,\"c\":3\n}", "{\n  \"a\": 1, \"b\": 2,\"c\":3\n}"},
+	}
+	for _, tt := range tests {
+		style := detectStyle([]byte(tt.original))
+		if got := string(style.apply([]byte(tt.edited))); got != tt.want {
+			t.Errorf("%s: apply = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenameMember(t *testing.T) {
+	var root jsonPath
+	tests := []struct {
+		data string
+		path jsonPath
+		name string
+		want string
+	}{
+		{`{"a":1,"b":2}`, root.child("a"), "c", `{"c":1,"b":2}`},
+		{"{\n  \"a\" : 1\n}", root.child("a"), "x.y", "{\n  \"x.y\" : 1\n}"},
+		{`{"o":{"k\"q":[1]}}`, root.child("o").child(`k"q`), "k", `{"o":{"k":[1]}}`},
+		{`{"a":1}`, root.child("a"), `new "name"`, `{"new \"name\"":1}`},
+		{`[{"a":{"b":true}}]`, root.element(0).child("a").child("b"), "ü", `[{"a":{"ü":true}}]`},
+	}
+	for _, tt := range tests {
+		got, err := renameMember([]byte(tt.data), tt.path, tt.name)
+		if err != nil {
+			t.Errorf("renameMember(%s, %s): %v", tt.data, tt.path, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("renameMember(%s, %s) = %s, want %s", tt.data, tt.path, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		data string
+		path jsonPath
+	}{
+		{`{"a":1}`, root.child("missing")},
+		{`{"a":[1,2]}`, root.child("a").element(1)},
+	} {
+		if _, err := renameMember([]byte(tt.data), tt.path, "x"); err == nil {
+			t.Errorf("renameMember(%s, %s) succeeded, want an error", tt.data, tt.path)
+		}
+	}
+}
+
+func TestEditKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.json")
+	m := newTestModel(t, "{\n  \"a\": 1,\n  \"b\": [\n    1\n  ]\n}\n")
+	m.jp.path = path
+	backspace := tea.KeyPressMsg{Code: tea.KeyBackspace}
+	enter := tea.KeyPressMsg{Code: tea.KeyEnter}
+
+	// the value prompt starts with the current value
+	m = send(m, ctrlKey('v'))
+	if m.edit == nil || m.edit.text != "1" {
+		t.Fatalf("value prompt = %+v", m.edit)
+	}
+	m = send(typeText(send(m, backspace), "42"), enter)
+	m = send(typeText(send(m, ctrlKey('r'), backspace), "c"), enter)
+	// a leaf adds a sibling: first the key, then its value
+	m = send(typeText(send(m, ctrlKey('o')), "d"), enter)
+	m = send(typeText(send(m, backspace, backspace, backspace, backspace), `{"x":true}`), enter)
+	if !m.dirty || !strings.Contains(m.renderHeader(), "modified") {
+		t.Error("the edits did not mark the document modified")
+	}
+	if got := m.rows[m.selectedIdx].key; got != "d" {
+		t.Errorf("selected %s after adding d", got)
+	}
+
+	// esc discards the prompt, ctrl+d deletes the selection
+	m = send(typeText(send(m, ctrlKey('r')), "zzz"), tea.KeyPressMsg{Code: tea.KeyEscape})
+	m.selectedIdx = slices.Index(rowKeys(m), "b[0]")
+	m = send(m, ctrlKey('d'), ctrlKey('w'))
+
+	want := "{\n  \"c\": 42,\n  \"b\": [],\n  \"d\": {\n    \"x\": true\n  }\n}\n"
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != want || m.dirty || m.status != "saved "+path {
+		t.Errorf("saved %q (status %q), want %q", saved, m.status, want)
+	}
+
+	// unsaved edits ask for confirmation before quitting
+	m = send(m, ctrlKey('v'), enter)
+	if _, cmd := m.Update(ctrlKey('c')); cmd != nil {
+		t.Error("ctrl+c quit with unsaved edits")
+	}
+}
+
+func TestRenameToCurrentName(t *testing.T) {
+	m := newTestModel(t, `{"a":1,"b":2}`)
+	m.edit = &editPrompt{op: editRename, path: jsonPath(nil).child("a"), text: "a"}
+	m.submitEdit()
+	if m.status != "" || m.dirty {
+		t.Errorf("renaming a to a: status %q, dirty %v", m.status, m.dirty)
+	}
+
+	m.edit = &editPrompt{op: editRename, path: jsonPath(nil).child("a"), text: "b"}
+	m.submitEdit()
+	if !strings.Contains(m.status, "already exists") {
+		t.Errorf("renaming a to b: status %q, want an error", m.status)
+	}
+}
+
+func TestEditJSONLinesKeepsOneRecordPerLine(t *testing.T) {
+	data := "{\"id\":1}\n{\n  \"id\": 2,\n  \"tags\": [\n    \"x\"\n  ]\n}\n{\"id\":3}\n"
+	m := newTestModel(t, data)
+	m.showRecord(0)
+	err := m.applyEdit(jsonPath(nil).child("id"), func(data []byte) ([]byte, error) {
+		return setValue(data, jsonPath(nil).child("id"), []byte("10"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "{\"id\":10}\n{\"id\":2,\"tags\":[\"x\"]}\n{\"id\":3}\n"
+	if got := string(m.jp.jsonData); got != want {
+		t.Errorf("saved stream = %q, want %q", got, want)
+	}
+	for i, record := range m.jp.records {
+		if got := string(m.jp.jsonData[record.base : record.base+len(record.jsonData)]); got != string(record.jsonData) {
+			t.Errorf("record %d is at the wrong offset: %q", i, got)
+		}
+	}
+}
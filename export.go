@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Formats accepted by -f, otherwise picked from the extension of the export file
+const (
+	exportJSON   = "json"   // indented JSON, one value after the other
+	exportNDJSON = "ndjson" // compact JSON, one value per line
+	exportYAML   = "yaml"
+	exportTOML   = "toml"
+	exportCSV    = "csv"
+)
+
+// bareTOMLKey matches the keys TOML accepts without quotes
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validExportFormat reports whether a -f value is known
+func validExportFormat(format string) bool {
+	switch format {
+	case exportJSON, exportNDJSON, exportYAML, exportTOML, exportCSV, typesGo, typesTS, typesSchema:
+		return true
+	}
+	return false
+}
+
+// exportFormatOf picks the format from a file extension, JSON for unknown extensions
+func exportFormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return exportNDJSON
+	case ".yaml", ".yml":
+		return exportYAML
+	case ".toml":
+		return exportTOML
+	case ".csv":
+		return exportCSV
+	case ".go":
+		return typesGo
+	case ".ts":
+		return typesTS
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".schema.json") {
+		return typesSchema
+	}
+	return exportJSON
+}
+
+// runExport writes the query result, or the whole input for an empty query, to path in format
+// the result goes to w when path is empty, the format is picked from path when format is empty
+func runExport(w io.Writer, path, format, query string, jp *JSONProcessor, opts outputOptions) error {
+	var results []gjson.Result
+	var name string
+	switch {
+	case query != "":
+		parsed, err := parsePath(query)
+		if err != nil {
+			return fmt.Errorf("invalid query %q: %w", query, err)
+		}
+		var ok bool
+		if results, ok = jp.query(query); !ok {
+			return fmt.Errorf("query failed: no matching data found for %q", query)
+		}
+		name = exportName(parsed)
+	case len(jp.records) > 0:
+		for _, record := range jp.records {
+			results = append(results, gjson.ParseBytes(record.jsonData))
+		}
+		name = "record"
+	default:
+		results = []gjson.Result{gjson.ParseBytes(jp.jsonData)}
+		name = "root"
+	}
+
+	if format == "" {
+		format = exportFormatOf(path)
+	}
+	data, err := exportResults(results, format, opts, name)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		_, err = w.Write(data)
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// exportName returns the last key of a path, used as the TOML key of values that are not tables
+// and as the name of the top level type of a type model
+func exportName(path jsonPath) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].kind == segmentKey {
+			return path[i].key
+		}
+	}
+	return "value"
+}
+
+// exportResults renders results in format
+// several results become a stream for JSON and YAML, the rows of a CSV file, an array for TOML
+// and are merged into a single type model
+func exportResults(results []gjson.Result, format string, opts outputOptions, name string) ([]byte, error) {
+	var out string
+	switch format {
+	case exportJSON, exportNDJSON:
+		opts.compact = opts.compact || format == exportNDJSON
+		opts.raw = false
+		values := make([]string, len(results))
+		for i, result := range results {
+			values[i] = formatOutput(result, opts)
+		}
+		out = strings.Join(values, "\n")
+	case exportYAML:
+		docs := make([]string, len(results))
+		for i, result := range results {
+			doc, err := encodeYAML(jsonToYAMLNode(result))
+			if err != nil {
+				return nil, err
+			}
+			docs[i] = doc
+		}
+		out = strings.Join(docs, "\n---\n")
+	case exportTOML:
+		var err error
+		if out, err = encodeTOML(tomlDocument(results, name)); err != nil {
+			return nil, err
+		}
+	case exportCSV:
+		var err error
+		if out, err = encodeCSV(results); err != nil {
+			return nil, err
+		}
+	case typesGo, typesTS, typesSchema:
+		var err error
+		if out, err = renderTypes(results, format, name); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	return []byte(strings.TrimSuffix(out, "\n") + "\n"), nil
+}
+
+// tomlDocument returns the table exported to TOML
+// TOML documents are tables, so other values are wrapped in a table under name
+func tomlDocument(results []gjson.Result, name string) gjson.Result {
+	if len(results) == 1 && results[0].IsObject() {
+		return results[0]
+	}
+	raw := make([]string, len(results))
+	for i, result := range results {
+		raw[i] = result.Raw
+		if !result.Exists() {
+			raw[i] = "null"
+		}
+	}
+	value := "[" + strings.Join(raw, ",") + "]"
+	if len(results) == 1 {
+		value = raw[0]
+	}
+	key, _ := json.Marshal(name)
+	return gjson.Parse("{" + string(key) + ":" + value + "}")
+}
+
+// encodeTOML renders an object as a TOML document in document order
+// nulls have no TOML equivalent and are left out
+func encodeTOML(doc gjson.Result) (string, error) {
+	var b strings.Builder
+	if err := writeTOMLTable(&b, nil, doc); err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(b.String(), "\n"), nil
+}
+
+// writeTOMLTable writes the values of an object, then its tables and arrays of tables
+func writeTOMLTable(b *strings.Builder, path []string, obj gjson.Result) error {
+	var err error
+	var tables []gjson.Result
+	var names []string
+	obj.ForEach(func(key, val gjson.Result) bool {
+		switch {
+		case val.Type == gjson.Null:
+		case val.IsObject() || isTableArray(val):
+			tables = append(tables, val)
+			names = append(names, key.String())
+		default:
+			var value string
+			if value, err = tomlValue(val); err != nil {
+				return false
+			}
+			fmt.Fprintf(b, "%s = %s\n", tomlKey(key.String()), value)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, table := range tables {
+		sub := append(path[:len(path):len(path)], names[i])
+		keys := make([]string, len(sub))
+		for j, name := range sub {
+			keys[j] = tomlKey(name)
+		}
+		header := strings.Join(keys, ".")
+		if table.IsObject() {
+			fmt.Fprintf(b, "\n[%s]\n", header)
+			if err := writeTOMLTable(b, sub, table); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, elem := range table.Array() {
+			fmt.Fprintf(b, "\n[[%s]]\n", header)
+			if err := writeTOMLTable(b, sub, elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTableArray reports whether a value is a non-empty array of objects, written as [[name]] tables
+func isTableArray(v gjson.Result) bool {
+	if !v.IsArray() {
+		return false
+	}
+	elems := v.Array()
+	for _, elem := range elems {
+		if !elem.IsObject() {
+			return false
+		}
+	}
+	return len(elems) > 0
+}
+
+// tomlValue renders a value inline, objects inside arrays become inline tables
+func tomlValue(v gjson.Result) (string, error) {
+	switch {
+	case v.Type == gjson.String:
+		return tomlString(v.String()), nil
+	case v.Type == gjson.Number, v.Type == gjson.True, v.Type == gjson.False:
+		return v.Raw, nil
+	case v.IsArray():
+		var values []string
+		for _, elem := range v.Array() {
+			if elem.Type == gjson.Null {
+				return "", errors.New("TOML arrays cannot hold null")
+			}
+			value, err := tomlValue(elem)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, value)
+		}
+		return "[" + strings.Join(values, ", ") + "]", nil
+	case v.IsObject():
+		var members []string
+		var err error
+		v.ForEach(func(key, val gjson.Result) bool {
+			if val.Type == gjson.Null {
+				return true
+			}
+			var value string
+			if value, err = tomlValue(val); err != nil {
+				return false
+			}
+			members = append(members, tomlKey(key.String())+" = "+value)
+			return true
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(members) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(members, ", ") + " }", nil
+	}
+	return "", errors.New("TOML cannot express null")
+}
+
+// tomlKey quotes a key unless it is a bare key
+func tomlKey(key string) string {
+	if bareTOMLKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString renders a basic string, JSON escapes are valid TOML escapes
+func tomlString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.ReplaceAll(strings.TrimSuffix(buf.String(), "\n"), "\x7f", `\u007f`)
+}
+
+// encodeCSV renders an array of objects, or several objects, as CSV
+// the header is the union of the keys, nested values are written as compact JSON
+func encodeCSV(results []gjson.Result) (string, error) {
+	rows := results
+	if len(results) == 1 && results[0].IsArray() {
+		rows = results[0].Array()
+	}
+
+	var header []string
+	seen := make(map[string]struct{})
+	for _, row := range rows {
+		if !row.IsObject() {
+			return "", errors.New("CSV export needs an array of objects")
+		}
+		row.ForEach(func(key, _ gjson.Result) bool {
+			if _, ok := seen[key.String()]; !ok {
+				seen[key.String()] = struct{}{}
+				header = append(header, key.String())
+			}
+			return true
+		})
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return "", err
+	}
+	for _, row := range rows {
+		cells := make(map[string]gjson.Result)
+		row.ForEach(func(key, val gjson.Result) bool {
+			if _, dup := cells[key.String()]; !dup {
+				cells[key.String()] = val
+			}
+			return true
+		})
+		record := make([]string, len(header))
+		for i, key := range header {
+			switch value := cells[key]; value.Type {
+			case gjson.Null:
+			case gjson.String:
+				record[i] = value.String()
+			default:
+				record[i] = cellText(value)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+// startExport opens the prompt for the file the selection is exported to
+func (m *Model) startExport() {
+	if _, err := m.selectionResults(); err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.edit = &editPrompt{op: editExport, prevCursor: m.searchCursor}
+	m.searchCursor = 0
+}
+
+// selectionName returns the last key of the selected path, "value" for jq results
+func (m *Model) selectionName() string {
+	if selected, ok := m.selectedPath(); ok && m.searchMode != searchJQ {
+		return exportName(selected)
+	}
+	return "value"
+}
+
+// exportSelection writes the values shown in the extractor to path, in the format of its extension
+func (m *Model) exportSelection(path string) error {
+	if path == "" {
+		return errors.New("no file name given")
+	}
+	results, err := m.selectionResults()
+	if err != nil {
+		return err
+	}
+	// the type model shown in the extractor is exported as it is
+	format := m.typeModel
+	if format == "" {
+		format = exportFormatOf(path)
+	}
+	data, err := exportResults(results, format, outputOptions{}, m.selectionName())
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path, data); err != nil {
+		return fmt.Errorf("exporting to %s: %w", path, err)
+	}
+	m.status = fmt.Sprintf("exported %d bytes to %s", len(data), path)
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/tidwall/gjson"
+)
+
+func TestEncodeTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{
+			"scalars in document order",
+			`{"title":"jex","port":8080,"ratio":0.5,"debug":false,"skip":null}`,
+			"title = \"jex\"\nport = 8080\nratio = 0.5\ndebug = false\n",
+		},
+		{
+			"tables after values",
+			`{"server":{"host":"a","tls":{"on":true}},"name":"x"}`,
+			"name = \"x\"\n\n[server]\nhost = \"a\"\n\n[server.tls]\non = true\n",
+		},
+		{
+			"arrays of tables",
+			`{"users":[{"name":"a"},{"name":"b","tags":["x","y"]}]}`,
+			"[[users]]\nname = \"a\"\n\n[[users]]\nname = \"b\"\ntags = [\"x\", \"y\"]\n",
+		},
+		{
+			"inline tables in mixed arrays",
+			`{"mixed":[1,{"a":1,"b":null}],"empty":{}}`,
+			"mixed = [1, { a = 1 }]\n\n[empty]\n",
+		},
+		{
+			"quoted keys and escapes",
+			`{"a.b":"line\nbreak","ü":"\u007f","bare-key_1":"\"q\""}`,
+			"\"a.b\" = \"line\\nbreak\"\n\"ü\" = \"\\u007f\"\nbare-key_1 = \"\\\"q\\\"\"\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := encodeTOML(gjson.Parse(tt.json))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: encodeTOML = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := encodeTOML(gjson.Parse(`{"a":[1,null]}`)); err == nil {
+		t.Error("encodeTOML accepted null in an array")
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []string
+		want    string
+	}{
+		{
+			"union of keys",
+			[]string{`[{"a":1,"b":"x"},{"b":"y","c":true}]`},
+			"a,b,c\n1,x,\n,y,true\n",
+		},
+		{
+			"nulls, nesting and quoting",
+			[]string{`[{"a":null,"b":{"k":[1,2]},"c":"x,\"y\""}]`},
+			"a,b,c\n,\"{\"\"k\"\":[1,2]}\",\"x,\"\"y\"\"\"\n",
+		},
+		{
+			"several objects",
+			[]string{`{"id":1}`, `{"id":2}`},
+			"id\n1\n2\n",
+		},
+	}
+	for _, tt := range tests {
+		var results []gjson.Result
+		for _, r := range tt.results {
+			results = append(results, gjson.Parse(r))
+		}
+		got, err := encodeCSV(results)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: encodeCSV = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{`[1,2]`, `[{"a":1},"b"]`, `"text"`} {
+		if _, err := encodeCSV([]gjson.Result{gjson.Parse(bad)}); err == nil {
+			t.Errorf("encodeCSV(%s) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestExportFormatOf(t *testing.T) {
+	tests := map[string]string{
+		"out.json":   exportJSON,
+		"out.JSONL":  exportNDJSON,
+		"out.ndjson": exportNDJSON,
+		"out.yml":    exportYAML,
+		"out.toml":   exportTOML,
+		"out.csv":    exportCSV,
+		"out.txt":    exportJSON,
+		"out":        exportJSON,
+	}
+	for path, want := range tests {
+		if got := exportFormatOf(path); got != want {
+			t.Errorf("exportFormatOf(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	jp := &JSONProcessor{jsonData: []byte(`{"items":[{"id":1,"tag":"a"},{"id":2}],"port":80}`)}
+	tests := []struct {
+		format, query, want string
+	}{
+		{exportJSON, "items[0]", "{\n  \"id\": 1,\n  \"tag\": \"a\"\n}\n"},
+		{exportNDJSON, "items[]", "{\"id\":1,\"tag\":\"a\"}\n{\"id\":2}\n"},
+		{exportYAML, "items[].id", "1\n---\n2\n"},
+		{exportTOML, "port", "port = 80\n"},
+		{exportCSV, "items", "id,tag\n1,a\n2,\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := runExport(&out, "", tt.format, tt.query, jp, outputOptions{}); err != nil {
+			t.Errorf("%s %s: %v", tt.format, tt.query, err)
+			continue
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.format, tt.query, out.String(), tt.want)
+		}
+	}
+
+	// the format follows the extension of the output file
+	path := filepath.Join(t.TempDir(), "items.csv")
+	if err := runExport(nil, path, "", "items", jp, outputOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "id,tag\n1,a\n2,\n" {
+		t.Errorf("items.csv = %q", data)
+	}
+
+	if err := runExport(io.Discard, "", exportJSON, "missing", jp, outputOptions{}); err == nil {
+		t.Error("exporting a missing key succeeded")
+	}
+	if err := runExport(io.Discard, "", exportCSV, "port", jp, outputOptions{}); err == nil {
+		t.Error("exporting a number to CSV succeeded")
+	}
+}
+
+func TestExportKey(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestModel(t, `{"items":[{"id":1},{"id":2}]}`)
+	m = send(typeText(send(m, tea.KeyPressMsg{Code: 'e', Mod: tea.ModAlt}), filepath.Join(dir, "items.yaml")), tea.KeyPressMsg{Code: tea.KeyEnter})
+	if !strings.HasPrefix(m.status, "exported ") {
+		t.Fatalf("status %q", m.status)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "items.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "- id: 1\n- id: 2\n" {
+		t.Errorf("items.yaml = %q", data)
+	}
+}
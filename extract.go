@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// extractScrollStep is the number of columns the extractor scrolls horizontally per key press
+const extractScrollStep = 4
+
+// extractMaxLines is the number of lines the extractor shows of a value,
+// highlighting a whole huge array would stall the UI
+const extractMaxLines = 5000
+
+// extractDelay is how long typing in the search bar has to pause before the extractor shows the
+// selected value, rendering a large value on every key press would stall typing
+const extractDelay = 150 * time.Millisecond
+
+// renderExtractMsg renders the extractor content it was scheduled for
+type renderExtractMsg struct {
+	id int
+}
+
+// scheduleExtract returns a command that renders the out of date extractor after extractDelay,
+// a later key press schedules a new render and the earlier one is dropped
+func (m *Model) scheduleExtract() tea.Cmd {
+	m.extractID++
+	id := m.extractID
+	return tea.Tick(extractDelay, func(time.Time) tea.Msg {
+		return renderExtractMsg{id: id}
+	})
+}
+
+// limitLines cuts content after extractMaxLines lines and notes how many were left out
+func limitLines(content string) string {
+	i, n := 0, 0
+	for ; n < extractMaxLines; n++ {
+		next := strings.IndexByte(content[i:], '\n')
+		if next < 0 {
+			return content
+		}
+		i += next + 1
+	}
+	rest := strings.Count(content[i:], "\n") + 1
+	return content[:i] + fmt.Sprintf("… %d more lines, open a range of the array to see them", rest)
+}
+
+// panelFocus is the panel that receives navigation keys
+type panelFocus int
+
+const (
+	focusTree    panelFocus = iota // keys move the tree selection and edit the search bar
+	focusExtract                   // keys scroll and search the extractor
+)
+
+// findMatch is the position of a find match in the extractor, in cells of its line
+type findMatch struct {
+	line, col, width int
+}
+
+// setExtractContent shows content in the extractor with the find matches highlighted
+// the view returns to the top when the content changed, e.g. after another node was selected
+func (m *Model) setExtractContent(content string) {
+	changed := content != m.extractRaw
+	m.extractRaw = content
+	m.findMatches = findMatches(content, m.findQuery)
+	if m.findIdx >= len(m.findMatches) {
+		m.findIdx = 0
+	}
+	if m.findQuery != "" {
+		content = highlightMatches(content, m.findQuery)
+	}
+	if m.ready {
+		// the table header takes lines from the viewport
+		height := m.height - 8
+		if m.tableHeader != "" {
+			height -= strings.Count(m.tableHeader, "\n") + 1
+		}
+		m.extractViewport.SetHeight(height)
+	}
+	m.extractViewport.SetContent(content)
+	if changed {
+		m.extractViewport.GotoTop()
+		m.extractViewport.SetXOffset(0)
+	}
+}
+
+// findMatches locates the case-insensitive occurrences of query in the visible text of content
+func findMatches(content, query string) []findMatch {
+	needle := foldRunes(query)
+	if len(needle) == 0 {
+		return nil
+	}
+	var matches []findMatch
+	for i, line := range strings.Split(ansi.Strip(content), "\n") {
+		runes := []rune(line)
+		folded := foldRunes(line)
+		for pos := 0; pos < len(folded); {
+			idx := indexRunes(folded[pos:], needle)
+			if idx < 0 {
+				break
+			}
+			start := pos + idx
+			pos = start + len(needle)
+			matches = append(matches, findMatch{
+				line:  i,
+				col:   ansi.StringWidth(string(runes[:start])),
+				width: ansi.StringWidth(string(runes[start:pos])),
+			})
+		}
+	}
+	return matches
+}
+
+// setFind changes the find query and jumps to its first match
+func (m *Model) setFind(query string) {
+	m.findQuery = query
+	m.findIdx = 0
+	m.updateExtractContent()
+	if query != "" {
+		m.showMatch(0)
+	}
+}
+
+// showMatch moves delta matches from the current one and scrolls it into view
+func (m *Model) showMatch(delta int) {
+	n := len(m.findMatches)
+	if n == 0 {
+		m.status = "no matches"
+		return
+	}
+	m.findIdx = ((m.findIdx+delta)%n + n) % n
+	match := m.findMatches[m.findIdx]
+	m.status = fmt.Sprintf("match %d/%d", m.findIdx+1, n)
+
+	vp := &m.extractViewport
+	if !vp.SoftWrap {
+		vp.EnsureVisible(match.line, match.col, match.col+match.width)
+		return
+	}
+	// with soft wrap the offset counts wrapped lines
+	width := max(vp.Width(), 1)
+	y := match.col / width
+	for _, line := range strings.Split(ansi.Strip(m.extractRaw), "\n")[:match.line] {
+		y += max(1, (ansi.StringWidth(line)+width-1)/width)
+	}
+	if y < vp.YOffset() || y >= vp.YOffset()+vp.Height() {
+		vp.SetYOffset(y)
+	}
+}
+
+// updateExtractKeys handles a key press while the extractor has focus
+// it reports false for keys that are not extractor commands so that they keep their global meaning
+func (m *Model) updateExtractKeys(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if m.tableHeader != "" && m.updateTableKeys(msg.String()) {
+		return true, nil
+	}
+	vp := &m.extractViewport
+	switch msg.String() {
+	case "up", "k":
+		vp.ScrollUp(1)
+	case "down", "j":
+		vp.ScrollDown(1)
+	case "pgup", "b":
+		vp.PageUp()
+	case "pgdown", "space", " ":
+		vp.PageDown()
+	case "g", "home":
+		vp.GotoTop()
+	case "G", "end":
+		vp.GotoBottom()
+	case "left", "h":
+		vp.ScrollLeft(extractScrollStep)
+	case "right", "l":
+		vp.ScrollRight(extractScrollStep)
+	case "w":
+		vp.SoftWrap = !vp.SoftWrap
+		vp.SetXOffset(0)
+		vp.GotoTop()
+	case "/":
+		m.edit = &editPrompt{op: editFind, text: m.findQuery, prevCursor: m.searchCursor}
+		m.searchCursor = len(m.edit.text)
+	case "n":
+		m.showMatch(1)
+	case "N":
+		m.showMatch(-1)
+	case "esc":
+		if m.findQuery != "" {
+			m.setFind("")
+		} else {
+			m.focus = focusTree
+		}
+	default:
+		// other printable keys would edit the hidden search bar
+		return msg.Key().Text != "", nil
+	}
+	return true, nil
+}
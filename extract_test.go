@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func TestFindMatches(t *testing.T) {
+	tests := []struct {
+		content, query string
+		want           []findMatch
+	}{
+		{"abc", "", nil},
+		{"one two\nTWO", "two", []findMatch{{0, 4, 3}, {1, 0, 3}}},
+		{"aaaa", "aa", []findMatch{{0, 0, 2}, {0, 2, 2}}},
+		// escape sequences are ignored, wide characters count two cells
+		{"\x1b[32m\"名前\"\x1b[0m: \"jex\"", "jex", []findMatch{{0, 9, 3}}},
+		{"名前", "前", []findMatch{{0, 2, 2}}},
+	}
+	for _, tt := range tests {
+		if got := findMatches(tt.content, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("findMatches(%q, %q) = %v, want %v", tt.content, tt.query, got, tt.want)
+		}
+	}
+}
+
+// extractModel returns a model with a small window whose extractor shows a long array
+func extractModel(t *testing.T) Model {
+	t.Helper()
+	items := make([]string, 40)
+	for i := range items {
+		items[i] = fmt.Sprintf(`"item %d"`, i)
+	}
+	m := newTestModel(t, `{"list":[`+strings.Join(items, ",")+`]}`)
+	return send(m, tea.WindowSizeMsg{Width: 100, Height: 20})
+}
+
+func TestExtractFocus(t *testing.T) {
+	tab := tea.KeyPressMsg{Code: tea.KeyTab}
+	esc := tea.KeyPressMsg{Code: tea.KeyEscape}
+	m := send(extractModel(t), tab)
+	if m.focus != focusExtract {
+		t.Fatal("tab did not focus the extractor")
+	}
+
+	// navigation keys scroll the extractor instead of moving the selection
+	m = typeText(m, "jjj")
+	if m.extractViewport.YOffset() != 3 || m.selectedIdx != 0 || m.searchQuery != "" {
+		t.Errorf("after jjj: offset %d, selected %d, search %q", m.extractViewport.YOffset(), m.selectedIdx, m.searchQuery)
+	}
+	m = typeText(m, "G")
+	if !m.extractViewport.AtBottom() {
+		t.Error("G did not go to the bottom")
+	}
+	m = typeText(m, "w")
+	if !m.extractViewport.SoftWrap || !strings.Contains(m.extractTitle(), "wrap") {
+		t.Errorf("w: wrap %v, title %q", m.extractViewport.SoftWrap, m.extractTitle())
+	}
+
+	// esc and tab go back to the tree
+	if m = send(m, esc); m.focus != focusTree {
+		t.Error("esc did not go back to the tree")
+	}
+	if m = send(m, tab, tab); m.focus != focusTree {
+		t.Error("tab did not toggle the focus")
+	}
+}
+
+func TestExtractFind(t *testing.T) {
+	m := typeText(send(extractModel(t), tea.KeyPressMsg{Code: tea.KeyTab}), "/")
+	if m.edit == nil || m.edit.op != editFind {
+		t.Fatal("/ did not open the find prompt")
+	}
+
+	// matches are found while typing, the first one is scrolled into view
+	m = typeText(m, "item 3")
+	if len(m.findMatches) != 11 || m.status != "match 1/11" {
+		t.Errorf("%d matches, status %q", len(m.findMatches), m.status)
+	}
+	m = send(m, tea.KeyPressMsg{Code: tea.KeyEnter})
+	if m.edit != nil || !strings.Contains(m.extractTitle(), `find "item 3"`) {
+		t.Errorf("enter: prompt %+v, title %q", m.edit, m.extractTitle())
+	}
+	first := m.findMatches[0].line
+	if y := m.extractViewport.YOffset(); first < y || first >= y+m.extractViewport.Height() {
+		t.Errorf("match on line %d not visible at offset %d", first, y)
+	}
+
+	// n and N cycle through the matches
+	m = typeText(m, "nn")
+	if m.status != "match 3/11" {
+		t.Errorf("after nn: status %q", m.status)
+	}
+	m = typeText(m, "NNN")
+	if m.status != "match 11/11" {
+		t.Errorf("after NNN: status %q", m.status)
+	}
+	last := m.findMatches[10].line
+	if y := m.extractViewport.YOffset(); last < y || last >= y+m.extractViewport.Height() {
+		t.Errorf("match on line %d not visible at offset %d", last, y)
+	}
+
+	// esc clears the find before leaving the extractor
+	m = send(m, tea.KeyPressMsg{Code: tea.KeyEscape})
+	if m.findQuery != "" || m.findMatches != nil || m.focus != focusExtract {
+		t.Errorf("esc: find %q, focus %d", m.findQuery, m.focus)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// newTestModel returns a model showing data, a stream of several values is opened as JSON Lines
+func newTestModel(t *testing.T, data string) Model {
+	t.Helper()
+	jp := &JSONProcessor{jsonData: []byte(data)}
+	if err := jp.parse(false); err != nil {
+		t.Fatal(err)
+	}
+	jp.extractKeys()
+	return NewBubbleteaModel(jp, "test.json")
+}
+
+// send feeds msgs to the model one at a time and returns the resulting model
+// the steps of a filter run before the next message, as they would when typing pauses
+func send(m Model, msgs ...tea.Msg) Model {
+	for _, msg := range msgs {
+		next, _ := m.Update(msg)
+		m = next.(Model)
+		for m.filterJob != nil {
+			next, _ = m.Update(filterStepMsg{id: m.filterJob.id})
+			m = next.(Model)
+		}
+	}
+	return m
+}
+
+// typeText feeds text to the model one key press per rune
+func typeText(m Model, text string) Model {
+	for _, r := range text {
+		m = send(m, tea.KeyPressMsg{Code: r, Text: string(r)})
+	}
+	return m
+}
+
+// ctrlKey returns the key press of ctrl and r
+func ctrlKey(r rune) tea.KeyPressMsg {
+	return tea.KeyPressMsg{Code: r, Mod: tea.ModCtrl}
+}
+
+// rowKeys returns the keys of the rows shown in the tree
+func rowKeys(m Model) []string {
+	var keys []string
+	for _, row := range m.rows {
+		keys = append(keys, row.key)
+	}
+	return keys
+}
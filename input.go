@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/tidwall/gjson"
+)
+
+// readChunkSize is the size of a single read from a stream
+const readChunkSize = 1 << 20
+
+// loadOptions describes where the input comes from and how to interpret it
+type loadOptions struct {
+	path      string             // empty for stdin
+	jsonLines bool               // treat the input as JSON Lines / NDJSON
+	schema    *jsonschema.Schema // schema the input is validated against, nil for none
+	recover   bool               // open a repaired document when the input is not valid JSON
+	jsonc     bool               // read the input as JSONC / JSON5
+}
+
+// name returns the input name shown to the user
+func (o loadOptions) name() string {
+	if o.path == "" {
+		return "stdin"
+	}
+	return o.path
+}
+
+// loadProgress reports how far loading has progressed
+type loadProgress struct {
+	stage string
+	read  int64
+	total int64 // 0 when the size is unknown
+}
+
+// loadInput reads and parses the input, reporting progress through report when non-nil
+func loadInput(opts loadOptions, report func(loadProgress)) (*JSONProcessor, error) {
+	if report == nil {
+		report = func(loadProgress) {}
+	}
+
+	data, err := readInput(opts.path, report)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", opts.name(), err)
+	}
+
+	report(loadProgress{stage: "Parsing", read: int64(len(data)), total: int64(len(data))})
+	jp := &JSONProcessor{jsonData: data, path: opts.path}
+	jsonLines := opts.jsonLines || isJSONLinesFile(opts.path)
+	// only plain JSON can be repaired, YAML and JSON5 input is not passed to recover
+	repairable := false
+	if isYAMLFile(opts.path) {
+		err = jp.parseYAML()
+	} else if opts.jsonc || isJSON5File(opts.path) {
+		err = jp.parseJSON5()
+	} else if jsonErr := jp.parse(jsonLines); jsonErr != nil {
+		err = jsonErr
+		repairable = true
+		if looksLikeYAML(data) {
+			// input that is not JSON may still be YAML, e.g. a manifest piped from kubectl
+			err = jp.parseYAML()
+		}
+		if err != nil && opts.recover {
+			err = jp.recover(jsonErr, jsonLines)
+		}
+	}
+	var parseErr *parseError
+	if errors.As(err, &parseErr) && repairable && !opts.recover {
+		return nil, fmt.Errorf("parsing %s: %w\nstart jex with -recover to open the valid parts", opts.name(), err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", opts.name(), err)
+	}
+	return jp, nil
+}
+
+// parse checks that the data is a JSON document, or splits it into records for JSON Lines input
+// a stream of several values is detected as JSON Lines even when jsonLines is false
+func (jp *JSONProcessor) parse(jsonLines bool) error {
+	if len(bytes.TrimSpace(jp.jsonData)) == 0 {
+		return errors.New("input is empty")
+	}
+	if jsonLines {
+		return jp.splitRecords()
+	}
+	if gjson.ValidBytes(jp.jsonData) {
+		return nil
+	}
+	if err := jp.splitRecords(); err == nil && len(jp.records) > 1 {
+		return nil
+	}
+	jp.records = nil
+	return syntaxError(jp.jsonData)
+}
+
+// readInput reads a file, or stdin when path is empty
+// regular files are memory-mapped where the platform supports it
+func readInput(path string, report func(loadProgress)) ([]byte, error) {
+	if path == "" {
+		return readStream(os.Stdin, 0, report)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !stat.Mode().IsRegular() {
+		return readStream(f, 0, report)
+	}
+	if data, err := mapFile(f, stat.Size()); err == nil {
+		report(loadProgress{stage: "Reading", read: stat.Size(), total: stat.Size()})
+		return data, nil
+	}
+	return readStream(f, stat.Size(), report)
+}
+
+// readStream reads r in chunks without any line length limit
+func readStream(r io.Reader, size int64, report func(loadProgress)) ([]byte, error) {
+	var buf bytes.Buffer
+	if size > 0 {
+		buf.Grow(int(size))
+	}
+	for {
+		buf.Grow(readChunkSize)
+		n, err := buf.ReadFrom(io.LimitReader(r, readChunkSize))
+		report(loadProgress{stage: "Reading", read: int64(buf.Len()), total: size})
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return buf.Bytes(), nil
+		}
+	}
+}
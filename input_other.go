@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// mapFile is not supported on this platform, files are read in chunks instead
+func mapFile(_ *os.File, _ int64) ([]byte, error) {
+	return nil, errors.New("memory mapping is not supported")
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// writeInput writes data to a file named name in a temporary directory and returns its path
+func writeInput(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadStream(t *testing.T) {
+	// a single line far longer than the 64 KiB limit of bufio.Scanner
+	line := `{"data":"` + strings.Repeat("x", 3*readChunkSize) + `"}`
+	var reports []loadProgress
+	data, err := readStream(strings.NewReader(line), int64(len(line)), func(p loadProgress) {
+		reports = append(reports, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != line {
+		t.Fatalf("read %d bytes, want %d", len(data), len(line))
+	}
+	if len(reports) < 3 {
+		t.Fatalf("got %d progress reports, want one per chunk", len(reports))
+	}
+	for i, p := range reports {
+		if p.total != int64(len(line)) || (i > 0 && p.read < reports[i-1].read) {
+			t.Errorf("report %d = %+v", i, p)
+		}
+	}
+	if last := reports[len(reports)-1]; last.read != int64(len(line)) {
+		t.Errorf("last report read %d bytes, want %d", last.read, len(line))
+	}
+}
+
+func TestLoadInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		opts    loadOptions
+		records int
+	}{
+		{"document", "a.json", `{"a":[1,2]}`, loadOptions{}, 0},
+		{"JSON Lines by extension", "a.jsonl", "{\"a\":1}\n", loadOptions{}, 1},
+		{"JSON Lines by flag", "a.json", "{\"a\":1}\n", loadOptions{jsonLines: true}, 1},
+		{"detected stream", "a.json", "{\"a\":1}\n{\"a\":2}\n", loadOptions{}, 2},
+	}
+	for _, tt := range tests {
+		tt.opts.path = writeInput(t, tt.file, tt.data)
+		jp, err := loadInput(tt.opts, nil)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(jp.jsonData) != tt.data || len(jp.records) != tt.records {
+			t.Errorf("%s: read %q with %d records, want %d", tt.name, jp.jsonData, len(jp.records), tt.records)
+		}
+	}
+
+	for _, tt := range []struct {
+		name, data, want string
+	}{
+		{"empty", "", "input is empty"},
+		{"blank", " \n", "input is empty"},
+		{"invalid", `{"a":}`, "invalid character '}'"},
+	} {
+		path := writeInput(t, "bad.json", tt.data)
+		_, err := loadInput(loadOptions{path: path}, nil)
+		if err == nil || !strings.Contains(err.Error(), "parsing "+path) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want parsing %s: …%s", tt.name, err, path, tt.want)
+		}
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := loadInput(loadOptions{path: missing}, nil); err == nil || !strings.HasPrefix(err.Error(), "reading "+missing) {
+		t.Errorf("missing file: error %v", err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestLoadingModel(t *testing.T) {
+	path := writeInput(t, "a.json", `{"name":"jex","tags":["a"]}`)
+	var m tea.Model = newLoadingModel([]loadOptions{{path: path}})
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	if !strings.Contains(m.(Model).renderLoading(), filepath.Base(path)) {
+		t.Error("the loading screen does not name the input")
+	}
+
+	// deliver the loader messages until the document is ready
+	for i := 0; m.(Model).loader != nil; i++ {
+		if i == 100 {
+			t.Fatal("loading did not finish")
+		}
+		var cmd tea.Cmd = m.(Model).loader.wait()
+		m, _ = m.Update(cmd())
+	}
+	loaded := m.(Model)
+	if !loaded.ready || loaded.width != 100 {
+		t.Error("the loaded model did not keep the window size")
+	}
+	if got := rowKeys(loaded); strings.Join(got, " ") != "name tags tags.# tags[0]" {
+		t.Errorf("rows = %v", got)
+	}
+
+	// errors end the program and are returned after it exits
+	m = newLoadingModel([]loadOptions{{path: writeInput(t, "bad.json", "{")}})
+	for m.(Model).loadErr == nil {
+		var cmd tea.Cmd
+		m, cmd = m.Update(m.(Model).loader.wait()())
+		if m.(Model).loadErr != nil && cmd == nil {
+			t.Error("a load error does not quit")
+		}
+	}
+}
+
+func TestReadInput(t *testing.T) {
+	// regular files are memory-mapped where the platform supports it
+	data := bytes.Repeat([]byte(`{"a":1}`+"\n"), 1000)
+	path := writeInput(t, "a.jsonl", string(data))
+	read, err := readInput(path, func(loadProgress) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(read, data) {
+		t.Error("readInput returned different data")
+	}
+}
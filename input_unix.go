@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mapFile maps a regular file into memory read-only
+// the mapping stays alive for the lifetime of the process
+func mapFile(f *os.File, size int64) ([]byte, error) {
+	if size == 0 {
+		return nil, errors.New("cannot map an empty file")
+	}
+	if int64(int(size)) != size {
+		return nil, errors.New("file too large to map")
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_PRIVATE)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/itchyny/gojq"
+)
+
+const (
+	// jqTimeout bounds the evaluation time of a single jq expression
+	jqTimeout = time.Second
+	// jqMaxResults limits the number of results rendered for a jq expression
+	jqMaxResults = 1000
+)
+
+// document returns the JSON data decoded into Go values, decoding it only once
+func (jp *JSONProcessor) document() (any, error) {
+	if !jp.decoded {
+		dec := json.NewDecoder(bytes.NewReader(jp.jsonData))
+		dec.UseNumber()
+		jp.docErr = dec.Decode(&jp.doc)
+		jp.decoded = true
+	}
+	return jp.doc, jp.docErr
+}
+
+// runJQ evaluates a jq expression against the JSON data
+// every result is rendered as indented JSON on its own line
+func (jp *JSONProcessor) runJQ(expr string) (string, error) {
+	values, truncated, err := jp.evalJQ(expr)
+	if err != nil {
+		return "", err
+	}
+	results := make([]string, 0, len(values))
+	for _, v := range values {
+		results = append(results, formatJQValue(v))
+	}
+	if truncated {
+		results = append(results, "... (truncated)")
+	}
+	return strings.Join(results, "\n"), nil
+}
+
+// evalJQ evaluates a jq expression against the JSON data and collects up to jqMaxResults results
+// truncated reports that further results were dropped, an empty expression behaves like "."
+func (jp *JSONProcessor) evalJQ(expr string) (values []any, truncated bool, err error) {
+	if strings.TrimSpace(expr) == "" {
+		expr = "."
+	}
+
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, false, err
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, false, err
+	}
+	// JSON Lines input is evaluated record by record like a jq input stream
+	sources := jp.records
+	if len(sources) == 0 {
+		sources = []*JSONProcessor{jp}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jqTimeout)
+	defer cancel()
+
+	for _, source := range sources {
+		input, err := source.document()
+		if err != nil {
+			return nil, false, err
+		}
+		iter := code.RunWithContext(ctx, input)
+		for {
+			v, ok := iter.Next()
+			if !ok {
+				break
+			}
+			if err, ok := v.(error); ok {
+				var haltErr *gojq.HaltError
+				if errors.As(err, &haltErr) && haltErr.Value() == nil {
+					return values, false, nil
+				}
+				return nil, false, err
+			}
+			if len(values) == jqMaxResults {
+				return values, true, nil
+			}
+			values = append(values, v)
+		}
+	}
+	return values, false, nil
+}
+
+// formatJQValue renders a jq result the way the jq command prints it
+func formatJQValue(v any) string {
+	raw, err := gojq.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	var prettyJSON bytes.Buffer
+	if err := json.Indent(&prettyJSON, raw, "", "  "); err == nil {
+		return prettyJSON.String()
+	}
+	return string(raw)
+}
+
+// formatJQOutput renders a jq result according to the output options
+func formatJQOutput(v any, opts outputOptions) string {
+	if str, ok := v.(string); ok && opts.raw {
+		return str
+	}
+	if opts.compact {
+		raw, err := gojq.Marshal(v)
+		if err != nil {
+			return err.Error()
+		}
+		return string(raw)
+	}
+	return formatJQValue(v)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+const jqDoc = `{"items":[{"name":"a","price":3},{"name":"b","price":12}],"owner":{"id":7}}`
+
+func TestRunJQ(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "{\n  \"items\": [\n    {\n      \"name\": \"a\",\n      \"price\": 3\n    },\n    {\n      \"name\": \"b\",\n      \"price\": 12\n    }\n  ],\n  \"owner\": {\n    \"id\": 7\n  }\n}"},
+		{".owner.id", "7"},
+		{".items | length", "2"},
+		{".owner | keys", "[\n  \"id\"\n]"},
+		{".items[] | select(.price > 5) | .name", `"b"`},
+		{".items | map(.price * 2)", "[\n  6,\n  24\n]"},
+		{`{total: (.items | map(.price) | add)}`, "{\n  \"total\": 15\n}"},
+		{".owner | to_entries[] | .key", `"id"`},
+		{".items[].name", "\"a\"\n\"b\""},
+		{".owner.id == 7", "true"},
+	}
+	jp := &JSONProcessor{jsonData: []byte(jqDoc)}
+	for _, tt := range tests {
+		got, err := jp.runJQ(tt.expr)
+		if err != nil {
+			t.Errorf("runJQ(%q): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("runJQ(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{".items[", "nosuchfunction", `.owner.id | error("stop")`} {
+		if got, err := jp.runJQ(expr); err == nil {
+			t.Errorf("runJQ(%q) = %q, want an error", expr, got)
+		}
+	}
+}
+
+func TestJQMode(t *testing.T) {
+	m := newTestModel(t, jqDoc)
+	m = send(m, tea.WindowSizeMsg{Width: 120, Height: 40}, ctrlKey('s'), ctrlKey('s'))
+	if m.searchMode != searchJQ {
+		t.Fatal("ctrl+s did not switch to jq mode")
+	}
+	m = typeText(m, ".items[0].name")
+	if !strings.Contains(m.jqResult, `"a"`) || strings.Contains(m.jqResult, `"b"`) {
+		t.Errorf("jq result = %q, want \"a\"", m.jqResult)
+	}
+	m = typeText(m, " |")
+	if !strings.HasPrefix(m.jqResult, "jq: ") {
+		t.Errorf("jq result of an incomplete expression = %q, want an error", m.jqResult)
+	}
+
+	// the key search query is kept while the jq expression is edited
+	m = send(m, ctrlKey('s'))
+	if m.searchMode != searchKeys || m.jqQuery != ".items[0].name |" || m.searchQuery != "" {
+		t.Errorf("back in key search: mode %d, jq %q, search %q", m.searchMode, m.jqQuery, m.searchQuery)
+	}
+}
+
+func TestJQModeClearsValueFilter(t *testing.T) {
+	m := newTestModel(t, jqDoc)
+	all := len(m.rows)
+	m = send(m, tea.WindowSizeMsg{Width: 120, Height: 40}, ctrlKey('s'))
+	m = typeText(m, "12")
+	if m.filter == nil || len(m.rows) >= all {
+		t.Fatalf("value search for 12 shows %d of %d rows", len(m.rows), all)
+	}
+	m = send(m, ctrlKey('s'))
+	if m.searchMode != searchJQ || m.filter != nil || len(m.rows) != all {
+		t.Errorf("jq mode shows %d of %d rows, filter %v", len(m.rows), all, m.filter)
+	}
+}
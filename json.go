@@ -3,25 +3,67 @@ package main
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"strings"
 
 	"github.com/alecthomas/chroma/quick"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 	"github.com/tidwall/gjson"
+	"gopkg.in/yaml.v3"
 )
 
 type JSONProcessor struct {
 	jsonData []byte
-	keys     []string
+	keys     []jsonPath
+
+	// records holds one processor per JSON Lines record or YAML document, empty for a single document
+	records []*JSONProcessor
+
+	// format is the syntax of the input, yamlNodes maps keys to the original YAML nodes
+	format    inputFormat
+	yamlNodes map[string]*yaml.Node
+
+	// source is the input that jsonData was converted from, nil for JSON read as is; sourceMap
+	// maps offsets back to it, nil when they are the same or for YAML, whose nodes know their lines
+	// comments holds the comments of JSONC / JSON5 members by key
+	source    []byte
+	sourceMap *sourceMap
+	comments  map[string]string
+
+	// lines indexes the lines of the input on first use, base is the offset of a JSON Lines record
+	lines []int
+	base  int
+
+	// path is the file the input was read from, empty for stdin
+	// style is the layout of the document, detected on the first edit
+	path  string
+	style *documentStyle
+
+	// schema is the schema given with --schema, violations the keywords the document fails
+	schema     *jsonschema.Schema
+	violations []violation
+
+	// parseErr is the syntax error of input opened with -recover, repairs the changes that made it valid
+	parseErr error
+	repairs  []repair
+
+	// decoded document, filled lazily for jq evaluation
+	doc     any
+	docErr  error
+	decoded bool
 }
 
 // JSONProcessor extract keys from JSON data
 // seenKeys is used to prevent duplicate keys
 // walk is a recursive function to walk through JSON data
 func (jp *JSONProcessor) extractKeys() {
+	if len(jp.records) > 0 {
+		jp.extractRecordKeys()
+		return
+	}
+
 	seenKeys := make(map[string]struct{})
-	var walk func(prefix string, value gjson.Result)
-	walk = func(prefix string, value gjson.Result) {
+	var walk func(prefix jsonPath, value gjson.Result)
+	walk = func(prefix jsonPath, value gjson.Result) {
 		if value.IsObject() {
 			jp.processObject(prefix, value, seenKeys, walk)
 		} else if value.IsArray() {
@@ -29,107 +71,63 @@ func (jp *JSONProcessor) extractKeys() {
 		}
 	}
 
-	parsed := gjson.ParseBytes(jp.jsonData)
-	walk("", parsed)
-
-	// Get root keys for validation
-	var rootKeys []string
-	if parsed.IsObject() {
-		parsed.ForEach(func(key, _ gjson.Result) bool {
-			rootKeys = append(rootKeys, key.String())
-			return true
-		})
-	}
+	walk(nil, gjson.ParseBytes(jp.jsonData))
 
 	// remove invalid keys
-	jp.keys = filterInvalidKeys(jp.keys, rootKeys)
+	jp.keys = filterInvalidKeys(jp.keys)
+}
+
+// addKey appends a key unless it was seen before
+func (jp *JSONProcessor) addKey(key jsonPath, seenKeys map[string]struct{}) {
+	id := key.String()
+	if _, exists := seenKeys[id]; !exists {
+		seenKeys[id] = struct{}{}
+		jp.keys = append(jp.keys, key)
+	}
 }
 
 // processObject processes JSON objects and extracts keys
-func (jp *JSONProcessor) processObject(prefix string, value gjson.Result, seenKeys map[string]struct{}, walk func(string, gjson.Result)) {
+func (jp *JSONProcessor) processObject(prefix jsonPath, value gjson.Result, seenKeys map[string]struct{}, walk func(jsonPath, gjson.Result)) {
 	value.ForEach(func(key, val gjson.Result) bool {
-		fullKey := key.String()
-		if prefix != "" {
-			fullKey = prefix + "." + fullKey
-		}
-		if _, exists := seenKeys[fullKey]; !exists {
-			seenKeys[fullKey] = struct{}{}
-			jp.keys = append(jp.keys, fullKey)
-		}
+		fullKey := prefix.child(key.String())
+		jp.addKey(fullKey, seenKeys)
 		walk(fullKey, val)
 		return true
 	})
 }
 
 // processArray processes JSON arrays and extracts keys
-func (jp *JSONProcessor) processArray(prefix string, value gjson.Result, seenKeys map[string]struct{}, walk func(string, gjson.Result)) {
-	arrayKey := fmt.Sprintf("%s.#", prefix)
-	if _, exists := seenKeys[arrayKey]; !exists {
-		seenKeys[arrayKey] = struct{}{}
-		jp.keys = append(jp.keys, arrayKey)
+// the members of object elements become column keys (e.g. foo[].name), collected from every element;
+// arrays longer than arrayBucketSize get no element keys, the tree loads their elements on demand
+func (jp *JSONProcessor) processArray(prefix jsonPath, value gjson.Result, seenKeys map[string]struct{}, walk func(jsonPath, gjson.Result)) {
+	jp.addKey(prefix.count(), seenKeys)
+	value.ForEach(func(_, val gjson.Result) bool {
+		if val.IsObject() {
+			val.ForEach(func(key, _ gjson.Result) bool {
+				jp.addKey(prefix.each().child(key.String()), seenKeys)
+				return true
+			})
+		}
+		return true
+	})
+	if arrayLength(value) > arrayBucketSize {
+		return
 	}
 	value.ForEach(func(index, val gjson.Result) bool {
-		elementKey := fmt.Sprintf("%s[%d]", prefix, index.Int())
-		if _, exists := seenKeys[elementKey]; !exists {
-			seenKeys[elementKey] = struct{}{}
-			jp.keys = append(jp.keys, elementKey)
-		}
+		elementKey := prefix.element(int(index.Int()))
+		jp.addKey(elementKey, seenKeys)
 		walk(elementKey, val)
 		return true
 	})
-	// add array element keys (e.g. foo[].name)
-	if prefix != "" {
-		value.ForEach(func(_, val gjson.Result) bool {
-			val.ForEach(func(key, val gjson.Result) bool {
-				fullKey := fmt.Sprintf("%s[].%s", prefix, key.String())
-				fullKey = strings.TrimSuffix(fullKey, ".")
-				if _, exists := seenKeys[fullKey]; !exists {
-					seenKeys[fullKey] = struct{}{}
-					jp.keys = append(jp.keys, fullKey)
-				}
-				// add nested array element keys (e.g. foo[].bar[0])
-				if val.IsArray() {
-					val.ForEach(func(index, nestedVal gjson.Result) bool {
-						nestedKey := fmt.Sprintf("%s[%d]", fullKey, index.Int())
-						if _, exists := seenKeys[nestedKey]; !exists {
-							seenKeys[nestedKey] = struct{}{}
-							jp.keys = append(jp.keys, nestedKey)
-						}
-						return true
-					})
-				}
-				return true
-			})
-			return false // only process the first element
-		})
-	}
 }
 
-func filterInvalidKeys(keys []string, rootKeys []string) []string {
-	var validKeys []string
+// filterInvalidKeys removes array projections (foo[].bar[0]) except the column keys of arrays (foo[].bar)
+func filterInvalidKeys(keys []jsonPath) []jsonPath {
+	var validKeys []jsonPath
 	for _, key := range keys {
-		// Remove keys ending with "[]"
-		if strings.HasSuffix(key, "[]") {
-			continue
-		}
-
-		if strings.Contains(key, "[]") {
+		if key.hasEach() && !key.isColumn() {
 			continue
 		}
-
-		if len(rootKeys) > 0 {
-			validRoot := false
-			for _, rootKey := range rootKeys {
-				if key == rootKey || strings.HasPrefix(key, rootKey+".") || strings.HasPrefix(key, rootKey+"[") {
-					validRoot = true
-					break
-				}
-			}
-			if !validRoot {
-				continue
-			}
-		}
-
 		validKeys = append(validKeys, key)
 	}
 	return validKeys
@@ -139,165 +137,41 @@ func filterInvalidKeys(keys []string, rootKeys []string) []string {
 
 // getParsedResult gets the parsed result for a given query
 func getParsedResult(query string, jsonData []byte) string {
-	if strings.Contains(query, "[]") {
-		return handleArrayQuery(query, jsonData)
-	}
-
-	if strings.Contains(query, "[") && strings.Contains(query, "]") {
-		return handleIndexedQuery(query, jsonData)
-	}
-
-	return handleOrdinaryQuery(query, jsonData)
-}
-
-// handleArrayQuery handles queries with [] pattern
-func handleArrayQuery(query string, jsonData []byte) string {
-	baseQuery := strings.Split(query, "[]")[0]
-	field := strings.TrimPrefix(strings.Split(query, "[]")[1], ".")
-	arrayResult := gjson.GetBytes(jsonData, baseQuery)
-	if arrayResult.IsArray() {
-		var values []string
-		arrayResult.ForEach(func(_, val gjson.Result) bool {
-			if strings.Contains(field, "[") && strings.Contains(field, "]") {
-				values = append(values, handleNestedArray(val, field)...)
-			} else if field == "" {
-				// Return entire array element
-				if val.IsObject() || val.IsArray() {
-					var prettyJSON bytes.Buffer
-					if err := json.Indent(&prettyJSON, []byte(val.Raw), "", "  "); err == nil {
-						values = append(values, prettyJSON.String())
-					} else {
-						values = append(values, val.Raw)
-					}
-				} else {
-					values = append(values, val.String())
-				}
-			} else {
-				result := val.Get(field)
-				if result.IsObject() || result.IsArray() {
-					var prettyJSON bytes.Buffer
-					if err := json.Indent(&prettyJSON, []byte(result.Raw), "", "  "); err == nil {
-						values = append(values, prettyJSON.String())
-					} else {
-						values = append(values, result.Raw)
-					}
-				} else {
-					values = append(values, result.String())
-				}
-			}
-			return true
-		})
-		return strings.Join(values, "\n")
-	}
-	return "Query failed. No matching data found."
-}
-
-// handleNestedArray handles nested array queries
-func handleNestedArray(val gjson.Result, field string) []string {
-	var values []string
-	nestedBase := strings.Split(field, "[")[0]
-	nestedIndex := strings.Split(strings.Split(field, "[")[1], "]")[0]
-	nestedField := ""
-	if strings.Contains(field, "]") {
-		nestedField = strings.TrimPrefix(strings.Split(field, "]")[1], ".")
+	results, ok := queryResults(query, jsonData)
+	if !ok {
+		return "Query failed. No matching data found."
 	}
-	nestedArray := val.Get(nestedBase)
-	if nestedArray.IsArray() {
-		nestedArray.ForEach(func(index, nestedVal gjson.Result) bool {
-			if index.String() == nestedIndex {
-				if nestedField != "" && strings.Contains(nestedField, "[") && strings.Contains(nestedField, "]") {
-					values = append(values, handleNestedArray(nestedVal, nestedField)...)
-				} else if nestedField != "" {
-					result := nestedVal.Get(nestedField)
-					if result.IsObject() || result.IsArray() {
-						var prettyJSON bytes.Buffer
-						if err := json.Indent(&prettyJSON, []byte(result.Raw), "", "  "); err == nil {
-							values = append(values, prettyJSON.String())
-						} else {
-							values = append(values, result.Raw)
-						}
-					} else {
-						values = append(values, result.String())
-					}
-				} else {
-					if nestedVal.IsObject() || nestedVal.IsArray() {
-						var prettyJSON bytes.Buffer
-						if err := json.Indent(&prettyJSON, []byte(nestedVal.Raw), "", "  "); err == nil {
-							values = append(values, prettyJSON.String())
-						} else {
-							values = append(values, nestedVal.Raw)
-						}
-					} else {
-						values = append(values, nestedVal.String())
-					}
-				}
-			}
-			return true
-		})
+	values := make([]string, 0, len(results))
+	for _, result := range results {
+		values = append(values, formatResult(result))
 	}
-	return values
+	return strings.Join(values, "\n")
 }
 
-// handleIndexedQuery handles queries with array indices like [0]
-func handleIndexedQuery(query string, jsonData []byte) string {
-	// Convert query from "company.departments[0].teams[0].members[0]"
-	// to gjson format "company.departments.0.teams.0.members.0"
-	gjsonQuery := strings.ReplaceAll(query, "[", ".")
-	gjsonQuery = strings.ReplaceAll(gjsonQuery, "]", "")
-
-	result := gjson.GetBytes(jsonData, gjsonQuery)
-	if result.Exists() {
-		// Pretty-print objects and arrays
-		if result.IsObject() || result.IsArray() {
-			var prettyJSON bytes.Buffer
-			if err := json.Indent(&prettyJSON, []byte(result.Raw), "", "  "); err == nil {
-				return prettyJSON.String()
-			}
-			return result.Raw
-		}
-		return result.String()
+// queryResults resolves a query to the matching values
+// ok is false when the query is not a valid path or does not match any data
+func queryResults(query string, jsonData []byte) (results []gjson.Result, ok bool) {
+	path, err := parsePath(query)
+	if err != nil {
+		return nil, false
 	}
-	return "Query failed. No matching data found."
+	return path.resolve(jsonData)
 }
 
-// handleOrdinaryQuery handles simple queries without arrays
-func handleOrdinaryQuery(query string, jsonData []byte) string {
-	result := gjson.GetBytes(jsonData, query)
-	if result.Exists() {
-		if result.IsObject() || result.IsArray() {
-			var prettyJSON bytes.Buffer
-			if err := json.Indent(&prettyJSON, []byte(result.Raw), "", "  "); err == nil {
-				return prettyJSON.String()
-			}
-			return result.Raw
+// formatResult pretty-prints objects and arrays and returns scalars as plain strings
+func formatResult(result gjson.Result) string {
+	if result.IsObject() || result.IsArray() {
+		var prettyJSON bytes.Buffer
+		if err := json.Indent(&prettyJSON, []byte(result.Raw), "", "  "); err == nil {
+			return prettyJSON.String()
 		}
-		return result.String()
+		return result.Raw
 	}
-	return "Query failed. No matching data found."
+	return result.String()
 }
 
 // Utility Functions
 
-// fuzzyFind checks if all characters in searchQuery are in key in order
-func fuzzyFind(key, searchQuery string) bool {
-	keyIndex := 0
-	for _, char := range searchQuery {
-		found := false
-		for keyIndex < len(key) {
-			if key[keyIndex] == byte(char) {
-				found = true
-				keyIndex++
-				break
-			}
-			keyIndex++
-		}
-		if !found {
-			return false
-		}
-	}
-	return true
-}
-
 // highlightJSON applies syntax highlighting to JSON
 func highlightJSON(jsonData string) string {
 	var highlighted bytes.Buffer
@@ -0,0 +1,574 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// jsoncFileNames are JSON files that are JSONC by convention, read with comments and trailing commas
+var jsoncFileNames = map[string]bool{
+	"tsconfig.json":      true,
+	"jsconfig.json":      true,
+	"devcontainer.json":  true,
+	".devcontainer.json": true,
+}
+
+// vscodeFileNames are the JSONC files of a .vscode directory
+var vscodeFileNames = map[string]bool{
+	"settings.json":    true,
+	"launch.json":      true,
+	"tasks.json":       true,
+	"extensions.json":  true,
+	"keybindings.json": true,
+}
+
+// isJSON5File reports whether the file extension or name marks JSONC or JSON5 input
+func isJSON5File(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonc", ".json5":
+		return true
+	}
+	name := filepath.Base(path)
+	return jsoncFileNames[name] || vscodeFileNames[name] && filepath.Base(filepath.Dir(path)) == ".vscode"
+}
+
+// commentStyle renders the comments of JSONC / JSON5 members in the extractor
+var commentStyle = lipgloss.NewStyle().Foreground(tnComment)
+
+// toggleComments shows or hides the comments of JSONC / JSON5 members
+func (m *Model) toggleComments() {
+	if m.jp.format != formatJSON5 {
+		m.status = "comments are only kept for JSONC / JSON5 input"
+		return
+	}
+	m.hideComments = !m.hideComments
+	m.updateExtractContent()
+}
+
+// commentReport renders the comments of a JSONC / JSON5 member, shown above its value in the extractor
+func (m *Model) commentReport(node *TreeNode) string {
+	doc := m.source()
+	comment := doc.comments[node.key]
+	if m.hideComments || m.diff != nil || comment == "" {
+		return ""
+	}
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		lines[i] = "// " + line
+	}
+	return commentStyle.Render(strings.Join(lines, "\n")) + "\n\n"
+}
+
+// parseJSON5 converts JSONC or JSON5 input into JSON data
+// the input is kept with a map back to it, and the comments in front of or behind a member are
+// kept for the extractor
+func (jp *JSONProcessor) parseJSON5() error {
+	c := &json5Converter{src: jp.jsonData, comments: make(map[string]string)}
+	c.out.Grow(len(jp.jsonData))
+	if err := c.convert(); err != nil {
+		return err
+	}
+	jp.format = formatJSON5
+	jp.source = jp.jsonData
+	jp.jsonData = c.out.Bytes()
+	jp.sourceMap = &c.srcMap
+	jp.comments = c.comments
+	return nil
+}
+
+// json5Comment is a comment read between two tokens
+type json5Comment struct {
+	text     string
+	sameLine bool // on the line of the token before it
+}
+
+// json5Converter writes JSONC or JSON5 as standard JSON
+// JSON5 is a superset of JSONC: comments, trailing commas, unquoted keys, single-quoted and
+// multi-line strings, hexadecimal numbers, leading and trailing decimal points, + signs,
+// Infinity and NaN, which have no JSON form and become null
+type json5Converter struct {
+	src      []byte
+	pos      int
+	out      bytes.Buffer
+	srcMap   sourceMap
+	comments map[string]string
+	pending  []json5Comment // comments read since the last token
+}
+
+// convert converts the whole input, which must hold a single value
+func (c *json5Converter) convert() error {
+	c.skipSpace()
+	if c.pos >= len(c.src) {
+		return c.errorf("input is empty")
+	}
+	// comments in front of the document describe the file, not its first member
+	c.pending = nil
+	if err := c.value(nil); err != nil {
+		return err
+	}
+	c.skipSpace()
+	if c.pos < len(c.src) {
+		return c.errorf("unexpected text after the document")
+	}
+	return nil
+}
+
+// errorf reports an error at the current position of the input
+func (c *json5Converter) errorf(format string, args ...any) error {
+	return newParseErrorAt(c.src, c.pos, fmt.Sprintf(format, args...), "")
+}
+
+// token marks the start of a token in the output
+func (c *json5Converter) token() {
+	c.srcMap.end(c.out.Len(), c.pos)
+	c.srcMap.add(c.out.Len(), c.pos)
+}
+
+// skipSpace skips whitespace and comments, collecting the comments in pending
+// it ends the token before it, whose converted form may differ in length, e.g. 0x10 and 16
+func (c *json5Converter) skipSpace() {
+	c.srcMap.end(c.out.Len(), c.pos)
+	sameLine := true
+	for c.pos < len(c.src) {
+		ch := c.src[c.pos]
+		switch {
+		case ch == '\n':
+			sameLine = false
+			c.pos++
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\v' || ch == '\f':
+			c.pos++
+		case ch == '/' && c.pos+1 < len(c.src) && c.src[c.pos+1] == '/':
+			end := bytes.IndexByte(c.src[c.pos:], '\n')
+			if end < 0 {
+				end = len(c.src) - c.pos
+			}
+			c.pending = append(c.pending, json5Comment{text: strings.TrimSpace(string(c.src[c.pos+2 : c.pos+end])), sameLine: sameLine})
+			c.pos += end
+		case ch == '/' && c.pos+1 < len(c.src) && c.src[c.pos+1] == '*':
+			end := bytes.Index(c.src[c.pos+2:], []byte("*/"))
+			if end < 0 {
+				// the error points at the start of the comment
+				return
+			}
+			c.pending = append(c.pending, json5Comment{text: blockCommentText(c.src[c.pos+2 : c.pos+2+end]), sameLine: sameLine})
+			c.pos += end + 4
+		case ch >= utf8.RuneSelf:
+			// JSON5 also allows the Unicode space separators, the BOM and the line separators
+			r, size := utf8.DecodeRune(c.src[c.pos:])
+			switch {
+			case r == '\u2028' || r == '\u2029':
+				sameLine = false
+			case r == '\ufeff' || unicode.Is(unicode.Zs, r):
+			default:
+				return
+			}
+			c.pos += size
+		default:
+			return
+		}
+	}
+}
+
+// blockCommentText strips the decoration of a /* */ comment, such as the * in front of every line
+func blockCommentText(body []byte) string {
+	lines := strings.Split(string(body), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if i > 0 {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		}
+		lines[i] = line
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// attach stores the pending comments for the member at path
+// trailing takes only the comments on the line of the member, the others lead the next one
+func (c *json5Converter) attach(path jsonPath, trailing bool) {
+	n := len(c.pending)
+	if trailing {
+		n = 0
+		for n < len(c.pending) && c.pending[n].sameLine {
+			n++
+		}
+	}
+	var texts []string
+	for _, comment := range c.pending[:n] {
+		if comment.text != "" {
+			texts = append(texts, comment.text)
+		}
+	}
+	c.pending = c.pending[n:]
+	if len(texts) == 0 || len(path) == 0 {
+		return
+	}
+	key := path.String()
+	if prev := c.comments[key]; prev != "" {
+		texts = append([]string{prev}, texts...)
+	}
+	c.comments[key] = strings.Join(texts, "\n")
+}
+
+// value converts the value at the current position, path is its path in the document
+func (c *json5Converter) value(path jsonPath) error {
+	if c.pos >= len(c.src) {
+		return c.errorf("unexpected end of input")
+	}
+	switch ch := c.src[c.pos]; {
+	case ch == '{':
+		return c.object(path)
+	case ch == '[':
+		return c.array(path)
+	case ch == '"' || ch == '\'':
+		return c.str()
+	case ch == '-' || ch == '+' || ch == '.' || ch >= '0' && ch <= '9' || hasWordPrefix(c.src[c.pos:], "Infinity") || hasWordPrefix(c.src[c.pos:], "NaN"):
+		return c.number()
+	}
+	for _, literal := range []string{"true", "false", "null"} {
+		if hasWordPrefix(c.src[c.pos:], literal) {
+			c.token()
+			c.out.WriteString(literal)
+			c.pos += len(literal)
+			return nil
+		}
+	}
+	return c.errorf("invalid character '%s' looking for a value", c.char())
+}
+
+// char returns the character at the current position
+func (c *json5Converter) char() string {
+	r, _ := utf8.DecodeRune(c.src[c.pos:])
+	return string(r)
+}
+
+// object converts an object, its members may have unquoted keys and a trailing comma
+func (c *json5Converter) object(path jsonPath) error {
+	c.token()
+	c.out.WriteByte('{')
+	c.pos++
+	for first := true; ; first = false {
+		c.skipSpace()
+		if c.pos >= len(c.src) {
+			return c.errorf("unexpected end of input in an object")
+		}
+		if c.src[c.pos] == '}' {
+			break
+		}
+		if !first {
+			c.out.WriteByte(',')
+		}
+
+		var key string
+		var err error
+		if ch := c.src[c.pos]; ch == '"' || ch == '\'' {
+			key, err = c.quotedKey()
+		} else {
+			key, err = c.identifier()
+		}
+		if err != nil {
+			return err
+		}
+		member := path.child(key)
+		c.attach(member, false)
+
+		c.skipSpace()
+		if c.pos >= len(c.src) || c.src[c.pos] != ':' {
+			if c.pos >= len(c.src) {
+				return c.errorf("unexpected end of input after an object key")
+			}
+			return c.errorf("invalid character '%s' after an object key, expected ':'", c.char())
+		}
+		c.out.WriteByte(':')
+		c.pos++
+		c.skipSpace()
+		c.attach(member, false)
+		if err := c.value(member); err != nil {
+			return err
+		}
+
+		if done, err := c.separator(member, '}'); done || err != nil {
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	c.attach(nil, false)
+	c.token()
+	c.out.WriteByte('}')
+	c.pos++
+	return nil
+}
+
+// array converts an array, it may have a trailing comma
+func (c *json5Converter) array(path jsonPath) error {
+	c.token()
+	c.out.WriteByte('[')
+	c.pos++
+	for i := 0; ; i++ {
+		c.skipSpace()
+		if c.pos >= len(c.src) {
+			return c.errorf("unexpected end of input in an array")
+		}
+		if c.src[c.pos] == ']' {
+			break
+		}
+		if i > 0 {
+			c.out.WriteByte(',')
+		}
+		element := path.element(i)
+		c.attach(element, false)
+		if err := c.value(element); err != nil {
+			return err
+		}
+		if done, err := c.separator(element, ']'); done || err != nil {
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	c.attach(nil, false)
+	c.token()
+	c.out.WriteByte(']')
+	c.pos++
+	return nil
+}
+
+// separator reads what follows a member or element: a comma, or the end of its container
+// comments on the same line belong to the member before them
+func (c *json5Converter) separator(path jsonPath, end byte) (done bool, err error) {
+	c.skipSpace()
+	c.attach(path, true)
+	if c.pos >= len(c.src) {
+		return false, c.errorf("unexpected end of input, expected ',' or '%c'", end)
+	}
+	switch c.src[c.pos] {
+	case ',':
+		c.pos++
+		comma := c.pos
+		c.skipSpace()
+		// a comment followed by a value on the same line leads that value
+		if c.pos >= len(c.src) || bytes.IndexByte(c.src[comma:c.pos], '\n') >= 0 {
+			c.attach(path, true)
+		}
+		return false, nil
+	case end:
+		return true, nil
+	}
+	return false, c.errorf("invalid character '%s', expected ',' or '%c'", c.char(), end)
+}
+
+// identifier reads an unquoted key and writes it as a JSON string
+func (c *json5Converter) identifier() (string, error) {
+	start := c.pos
+	c.token()
+	if !isIdentStart(c.src[c.pos]) {
+		return "", c.errorf("invalid character '%s' looking for an object key", c.char())
+	}
+	for c.pos < len(c.src) && isIdentPart(c.src[c.pos]) {
+		c.pos++
+	}
+	key := string(c.src[start:c.pos])
+	if !utf8.ValidString(key) {
+		c.pos = start
+		return "", c.errorf("invalid UTF-8 in an object key")
+	}
+	writeJSONString(&c.out, key)
+	return key, nil
+}
+
+// quotedKey reads a quoted key, writes it as a JSON string and returns its value
+func (c *json5Converter) quotedKey() (string, error) {
+	start := c.out.Len()
+	if err := c.str(); err != nil {
+		return "", err
+	}
+	var key string
+	err := json.Unmarshal(c.out.Bytes()[start:], &key)
+	return key, err
+}
+
+// str reads a string quoted with double or single quotes and writes it as a JSON string
+// the JSON5 escapes \', \v, \0, \x and line continuations are rewritten
+func (c *json5Converter) str() error {
+	quote := c.src[c.pos]
+	start := c.pos
+	c.token()
+	c.pos++
+	c.out.WriteByte('"')
+	for {
+		if c.pos >= len(c.src) || c.src[c.pos] == '\n' || c.src[c.pos] == '\r' {
+			c.pos = start
+			return c.errorf("string is not closed")
+		}
+		ch := c.src[c.pos]
+		switch {
+		case ch == quote:
+			c.out.WriteByte('"')
+			c.pos++
+			return nil
+		case ch == '\\':
+			if err := c.escape(); err != nil {
+				return err
+			}
+			continue
+		case ch == '"':
+			c.out.WriteString(`\"`)
+		case ch < ' ':
+			fmt.Fprintf(&c.out, `\u%04x`, ch)
+		default:
+			c.out.WriteByte(ch)
+		}
+		c.pos++
+	}
+}
+
+// escape rewrites the escape sequence at the current position
+func (c *json5Converter) escape() error {
+	if c.pos+1 >= len(c.src) {
+		return c.errorf("string is not closed")
+	}
+	next := c.src[c.pos+1]
+	c.pos += 2
+	switch next {
+	case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+		c.out.WriteByte('\\')
+		c.out.WriteByte(next)
+	case '\'':
+		c.out.WriteByte('\'')
+	case 'v':
+		c.out.WriteString(`\u000b`)
+	case '0':
+		c.out.WriteString(`\u0000`)
+	case 'x', 'u':
+		digits := 2
+		if next == 'u' {
+			digits = 4
+		}
+		if c.pos+digits > len(c.src) || !isHex(c.src[c.pos:c.pos+digits]) {
+			c.pos -= 2
+			return c.errorf("invalid escape sequence in a string")
+		}
+		c.out.WriteString(`\u` + strings.Repeat("0", 4-digits))
+		c.out.Write(c.src[c.pos : c.pos+digits])
+		c.pos += digits
+	case '\n':
+		// a line continuation
+	case '\r':
+		if c.pos < len(c.src) && c.src[c.pos] == '\n' {
+			c.pos++
+		}
+	default:
+		// any other character stands for itself, U+2028 and U+2029 continue the line
+		c.pos--
+		r, size := utf8.DecodeRune(c.src[c.pos:])
+		switch {
+		case r == '\u2028' || r == '\u2029':
+		case r < ' ':
+			fmt.Fprintf(&c.out, `\u%04x`, r)
+		default:
+			c.out.WriteRune(r)
+		}
+		c.pos += size
+	}
+	return nil
+}
+
+// number reads a JSON5 number and writes its JSON form
+func (c *json5Converter) number() error {
+	start := c.pos
+	c.token()
+	for c.pos < len(c.src) && (isIdentPart(c.src[c.pos]) || bytes.IndexByte([]byte("+-."), c.src[c.pos]) >= 0) {
+		// a sign only continues a number after a decimal exponent
+		if ch := c.src[c.pos]; (ch == '+' || ch == '-') && c.pos > start && (c.src[c.pos-1] != 'e' && c.src[c.pos-1] != 'E' || isHexNumber(c.src[start:c.pos])) {
+			break
+		}
+		c.pos++
+	}
+	token := string(c.src[start:c.pos])
+	raw, ok := json5Number(token)
+	if !ok {
+		c.pos = start
+		return c.errorf("invalid number %s", token)
+	}
+	c.out.WriteString(raw)
+	return nil
+}
+
+// isHexNumber reports whether a number token so far is hexadecimal
+func isHexNumber(token []byte) bool {
+	token = bytes.TrimLeft(token, "+-")
+	return len(token) > 1 && token[0] == '0' && (token[1] == 'x' || token[1] == 'X')
+}
+
+// json5Number converts a JSON5 number to JSON, Infinity and NaN become null
+func json5Number(token string) (string, bool) {
+	sign := ""
+	digits := token
+	if digits != "" && (digits[0] == '+' || digits[0] == '-') {
+		if digits[0] == '-' {
+			sign = "-"
+		}
+		digits = digits[1:]
+	}
+	switch {
+	case digits == "Infinity" || digits == "NaN":
+		return "null", true
+	case len(digits) > 2 && digits[0] == '0' && (digits[1] == 'x' || digits[1] == 'X'):
+		v, err := strconv.ParseUint(digits[2:], 16, 64)
+		if err != nil {
+			return "", false
+		}
+		return sign + strconv.FormatUint(v, 10), true
+	}
+	// .5 and 5. are written 0.5 and 5
+	if strings.HasPrefix(digits, ".") {
+		digits = "0" + digits
+	}
+	if i := strings.IndexByte(digits, '.'); i >= 0 && (i+1 == len(digits) || digits[i+1] < '0' || digits[i+1] > '9') {
+		digits = digits[:i] + digits[i+1:]
+	}
+	number := sign + digits
+	// numbers out of the float64 range, e.g. 1e400, are valid JSON and kept as written
+	if _, err := strconv.ParseFloat(number, 64); err != nil && !errors.Is(err, strconv.ErrRange) || !validJSONNumber(number) {
+		return "", false
+	}
+	return number, true
+}
+
+// validJSONNumber reports whether s is a number in JSON syntax
+func validJSONNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return false
+	}
+	// JSON has no leading zeros
+	return len(s) == 1 || s[0] != '0' || s[1] < '0' || s[1] > '9'
+}
+
+// writeJSONString writes s as a JSON string
+func writeJSONString(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ':
+			fmt.Fprintf(b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/tidwall/gjson"
+)
+
+func TestParseJSON5(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain JSON", `{"a": [1, "x", true, null]}`, `{"a":[1,"x",true,null]}`},
+		{"comments", "// file\n{\n  /* block */ \"a\": 1, // trailing\n}", `{"a":1}`},
+		{"trailing commas", `{"a": [1, 2,], "b": {},}`, `{"a":[1,2],"b":{}}`},
+		{"unquoted keys", `{a: 1, $b_2: 2, ü: 3}`, `{"a":1,"$b_2":2,"ü":3}`},
+		{"single quotes", `{'a': 'it''s'}`, ``},
+		{"single quoted strings", `['a "b"', 'c\'d']`, `["a \"b\"","c'd"]`},
+		{"hexadecimal", `[0x10, -0xFF, +0x1]`, `[16,-255,1]`},
+		{"decimal points", `[.5, 5., -.5, +1, 1e3]`, `[0.5,5,-0.5,1,1e3]`},
+		{"out of range", `[1e400, -1e400, 1e-400]`, `[1e400,-1e400,1e-400]`},
+		{"Infinity and NaN", `[Infinity, -Infinity, NaN]`, `[null,null,null]`},
+		{"escapes", `'\x41\v\0\/'`, `"\u0041\u000b\u0000\/"`},
+		{"line continuation", "'a\\\nb'", `"ab"`},
+	}
+	for _, tt := range tests {
+		jp := &JSONProcessor{jsonData: []byte(tt.in)}
+		err := jp.parseJSON5()
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: parseJSON5(%s) = %s, want an error", tt.name, tt.in, jp.jsonData)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := string(jp.jsonData); got != tt.want {
+			t.Errorf("%s: parseJSON5(%s) = %s, want %s", tt.name, tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{``, `{a b}`, `[1 2]`, `[01]`, `[0xZ]`, `'open`, `/* open`, `{"a":1} x`, `[undefined]`} {
+		jp := &JSONProcessor{jsonData: []byte(bad)}
+		if err := jp.parseJSON5(); err == nil {
+			t.Errorf("parseJSON5(%s) = %s, want an error", bad, jp.jsonData)
+		}
+	}
+}
+
+func TestJSON5Comments(t *testing.T) {
+	in := `// describes the file
+{
+  // the name
+  name: "web", // shown in the list
+  /*
+   * replica count
+   * at least one
+   */
+  replicas: 2,
+  ports: [
+    80, // http
+    443,
+  ],
+}`
+	jp := &JSONProcessor{jsonData: []byte(in)}
+	if err := jp.parseJSON5(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"name":     "the name\nshown in the list",
+		"replicas": "replica count\nat least one",
+		"ports[0]": "http",
+	}
+	if !maps.Equal(jp.comments, want) {
+		t.Errorf("comments = %q, want %q", jp.comments, want)
+	}
+}
+
+func TestIsJSON5File(t *testing.T) {
+	tests := map[string]bool{
+		"config.jsonc":            true,
+		"config.JSON5":            true,
+		"app/tsconfig.json":       true,
+		".devcontainer.json":      true,
+		"devcontainer.json":       true,
+		"proj/.vscode/tasks.json": true,
+		"proj/tasks.json":         false,
+		"config.json":             false,
+	}
+	for path, want := range tests {
+		if got := isJSON5File(path); got != want {
+			t.Errorf("isJSON5File(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestCommentReport(t *testing.T) {
+	path := writeInput(t, "app.jsonc", "{\n  // the port\n  port: 80,\n  host: 'x',\n}\n")
+	jp, err := loadInput(loadOptions{path: path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jp.format != formatJSON5 || string(jp.jsonData) != `{"port":80,"host":"x"}` {
+		t.Fatalf("loaded %q as format %d", jp.jsonData, jp.format)
+	}
+	jp.extractKeys()
+	m := NewBubbleteaModel(jp, path)
+	if got := ansi.Strip(m.commentReport(m.rows[0])); !strings.Contains(got, "// the port\n") {
+		t.Errorf("report of port = %q", got)
+	}
+	if got := m.commentReport(m.rows[1]); got != "" {
+		t.Errorf("report of host = %q", got)
+	}
+
+	// alt+c hides the report, editing is refused
+	m = send(m, tea.KeyPressMsg{Code: 'c', Mod: tea.ModAlt})
+	if m.commentReport(m.rows[0]) != "" {
+		t.Error("alt+c did not hide the comments")
+	}
+	if m = send(m, ctrlKey('v')); m.edit != nil || !strings.Contains(m.status, "not supported") {
+		t.Errorf("ctrl+v on JSONC input: prompt %v, status %q", m.edit, m.status)
+	}
+}
+
+func TestSourceOffset(t *testing.T) {
+	// {a: 0x10} converts to {"a":16}
+	s := &sourceMap{}
+	s.add(0, 0)
+	s.add(1, 1)
+	s.add(5, 4)
+	s.add(7, 8)
+	for out, want := range map[int]int{0: 0, 2: 2, 5: 4, 6: 5, 7: 8} {
+		if got := s.sourceOffset(out); got != want {
+			t.Errorf("sourceOffset(%d) = %d, want %d", out, got, want)
+		}
+	}
+}
+
+func TestJSON5SourceSpans(t *testing.T) {
+	in := "{\n  a: 0x10, // hex\n  b: .5,\n  c: 'x\\ty',\n}"
+	jp := &JSONProcessor{jsonData: []byte(in)}
+	if err := jp.parseJSON5(); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"a": "0x10", "b": ".5", "c": `'x\ty'`} {
+		value := gjson.GetBytes(jp.jsonData, key)
+		start, end := jp.toSource(value.Index), jp.toSourceEnd(value.Index+len(value.Raw))
+		if got := in[start:end]; got != want {
+			t.Errorf("%s: source span %q, want %q", key, got, want)
+		}
+	}
+}
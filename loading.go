@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"charm.land/bubbles/v2/progress"
+	"charm.land/bubbles/v2/spinner"
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// loadingBarWidth is the width of the loading progress bar
+const loadingBarWidth = 40
+
+// loadProgressMsg reports loading progress to the TUI
+type loadProgressMsg loadProgress
+
+// loadedMsg delivers the loaded documents, or the error that stopped loading
+type loadedMsg struct {
+	docs []*JSONProcessor
+	err  error
+}
+
+// loader reads, parses and indexes the inputs in the background
+// diff mode loads two inputs, otherwise there is a single one
+type loader struct {
+	inputs   []loadOptions
+	progress chan loadProgress
+	done     chan loadedMsg
+}
+
+// startLoader starts loading the inputs in a new goroutine
+func startLoader(inputs []loadOptions) *loader {
+	l := &loader{
+		inputs:   inputs,
+		progress: make(chan loadProgress, 1),
+		done:     make(chan loadedMsg, 1),
+	}
+	go l.run()
+	return l
+}
+
+// run loads the inputs and extracts their keys
+func (l *loader) run() {
+	var docs []*JSONProcessor
+	for _, opts := range l.inputs {
+		jp, err := loadInput(opts, l.report)
+		if err != nil {
+			l.done <- loadedMsg{err: err}
+			return
+		}
+		size := int64(len(jp.jsonData))
+		l.report(loadProgress{stage: "Indexing keys", read: size, total: size})
+		jp.extractKeys()
+		if opts.schema != nil {
+			l.report(loadProgress{stage: "Validating", read: size, total: size})
+			if err := jp.validate(opts.schema); err != nil {
+				l.done <- loadedMsg{err: fmt.Errorf("validating %s: %w", opts.name(), err)}
+				return
+			}
+		}
+		docs = append(docs, jp)
+	}
+	l.done <- loadedMsg{docs: docs}
+}
+
+// report publishes progress without blocking, dropping updates the TUI has not picked up yet
+func (l *loader) report(p loadProgress) {
+	select {
+	case <-l.progress:
+	default:
+	}
+	select {
+	case l.progress <- p:
+	default:
+	}
+}
+
+// wait returns a command that delivers the next progress update or the final result
+func (l *loader) wait() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-l.done:
+			return msg
+		case p := <-l.progress:
+			return loadProgressMsg(p)
+		}
+	}
+}
+
+// newLoadingModel creates a model that shows loading progress until the inputs are ready
+func newLoadingModel(inputs []loadOptions) Model {
+	names := make([]string, len(inputs))
+	for i, opts := range inputs {
+		names[i] = opts.name()
+	}
+	return Model{
+		fileName:    strings.Join(names, " → "),
+		loader:      startLoader(inputs),
+		loadSpinner: spinner.New(spinner.WithSpinner(spinner.Dot)),
+		loadBar:     progress.New(progress.WithColors(tnBlue, tnPurple), progress.WithWidth(loadingBarWidth)),
+	}
+}
+
+// updateLoading handles messages while the input is loading
+func (m Model) updateLoading(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.loadSpinner, cmd = m.loadSpinner.Update(msg)
+		return m, cmd
+
+	case loadProgressMsg:
+		m.loadProgress = loadProgress(msg)
+		return m, m.loader.wait()
+
+	case loadedMsg:
+		if msg.err != nil {
+			m.loadErr = msg.err
+			return m, tea.Quit
+		}
+		var loaded Model
+		if inputs := m.loader.inputs; len(inputs) == 2 {
+			loaded = NewDiffModel(msg.docs[0], msg.docs[1], inputs[0].name(), inputs[1].name())
+		} else {
+			loaded = NewBubbleteaModel(msg.docs[0], m.fileName)
+		}
+		if m.width == 0 {
+			return loaded, nil
+		}
+		return loaded.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+	}
+	return m, nil
+}
+
+// renderLoading renders the loading progress
+func (m Model) renderLoading() string {
+	stage := m.loadProgress.stage
+	if stage == "" {
+		stage = "Reading"
+	}
+	spin := lipgloss.NewStyle().Foreground(tnBlue).Render(m.loadSpinner.View())
+	status := fmt.Sprintf("%s %s %s", spin, stage, m.fileName)
+
+	size := formatBytes(m.loadProgress.read)
+	if m.loadProgress.total > 0 {
+		size += " / " + formatBytes(m.loadProgress.total)
+	}
+
+	lines := []string{status, size}
+	if m.loadProgress.total > 0 {
+		lines = append(lines, m.loadBar.ViewAs(float64(m.loadProgress.read)/float64(m.loadProgress.total)))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, lines...)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
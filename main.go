@@ -1,51 +1,116 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"os"
-	"strings"
 )
 
 func main() {
-	var jsonStr strings.Builder
+	query := flag.String("q", "", "evaluate `path` without starting the TUI and print the result")
+	printMode := flag.String("p", printKey, "what enter prints on exit: key, value or both")
+	raw := flag.Bool("r", false, "print strings without JSON quotes")
+	compact := flag.Bool("c", false, "print objects and arrays on a single line")
+	jsonLines := flag.Bool("ndjson", false, "treat the input as JSON Lines / NDJSON (detected automatically by default)")
+	output := flag.String("o", "", "write the query result, or the whole input, to `file` instead of stdout")
+	schemaPath := flag.String("schema", "", "validate the input against the JSON Schema in `file` and mark the errors in the tree")
+	jsonc := flag.Bool("jsonc", false, "read the input as JSONC / JSON5 (detected from .jsonc, .json5, tsconfig.json and the like by default)")
+	recoverInput := flag.Bool("recover", false, "open a best-effort repair of input that is not valid JSON instead of failing")
+	format := flag.String("f", "", "output format: json, ndjson, yaml, toml, csv, or the type model as go, ts or schema (default: from the -o extension, else json)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: jex [flags] <JSON_FILE> or cat <JSON_FILE> | jex [flags]")
+		fmt.Fprintln(os.Stderr, "       jex [flags] diff <OLD_FILE> <NEW_FILE>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	switch *printMode {
+	case printKey, printValue, printBoth:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid -p value %q (want key, value or both)\n", *printMode)
+		os.Exit(2)
+	}
+	if *format != "" && !validExportFormat(*format) {
+		fmt.Fprintf(os.Stderr, "Error: invalid -f value %q (want json, ndjson, yaml, toml, csv, go, ts or schema)\n", *format)
+		os.Exit(2)
+	}
+	opts := outputOptions{raw: *raw, compact: *compact}
+
+	// Diff mode: compare two documents in a merged tree
+	if flag.Arg(0) == "diff" {
+		if flag.NArg() != 3 || *query != "" || *output != "" || *format != "" || *schemaPath != "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+		runTUI(*printMode, opts,
+			loadOptions{path: flag.Arg(1), jsonLines: *jsonLines, recover: *recoverInput, jsonc: *jsonc},
+			loadOptions{path: flag.Arg(2), jsonLines: *jsonLines, recover: *recoverInput, jsonc: *jsonc})
+		return
+	}
 
-	if len(os.Args) > 1 {
-		filePath := os.Args[1]
-		data, err := os.ReadFile(filePath)
+	load := loadOptions{path: flag.Arg(0), jsonLines: *jsonLines, recover: *recoverInput, jsonc: *jsonc}
+	if *schemaPath != "" {
+		schema, err := loadSchema(*schemaPath)
 		if err != nil {
-			fmt.Println("Error reading file:", err)
-			return
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
 		}
-		jsonStr.Write(data)
-	} else {
+		load.schema = schema
+	}
+	if load.path == "" {
 		stat, _ := os.Stdin.Stat()
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			scanner := bufio.NewScanner(os.Stdin)
-			for scanner.Scan() {
-				jsonStr.WriteString(scanner.Text() + "\n")
-			}
-		} else {
-			fmt.Println("Usage: jex <JSON_FILE> or cat <JSON_FILE> | jex")
-			return
+		if (stat.Mode() & os.ModeCharDevice) != 0 {
+			flag.Usage()
+			os.Exit(2)
 		}
 	}
 
-	jp := &JSONProcessor{
-		jsonData: []byte(jsonStr.String()),
+	// Headless mode: print or export the query result and exit
+	if *query != "" || *output != "" || *format != "" {
+		if load.schema != nil {
+			fmt.Fprintln(os.Stderr, "Error: -schema marks errors in the TUI and cannot be combined with -q, -o or -f")
+			os.Exit(2)
+		}
+		jp, err := loadInput(load, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		if jp.parseErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: parsing %s: %v\n", load.name(), jp.parseErr)
+			for _, r := range jp.repairs {
+				fmt.Fprintln(os.Stderr, "repaired", r)
+			}
+		}
+		if *output != "" || *format != "" {
+			err = runExport(os.Stdout, *output, *format, *query, jp, opts)
+		} else {
+			err = runQuery(os.Stdout, *query, jp, opts)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	jp.extractKeys()
+	// Use new Bubbletea TUI, the input is loaded in the background
+	runTUI(*printMode, opts, load)
+}
 
-	// Determine filename for display
-	fileName := "stdin"
-	if len(os.Args) > 1 {
-		fileName = os.Args[1]
+// runTUI runs the TUI on the inputs and prints the node picked with enter
+func runTUI(printMode string, opts outputOptions, inputs ...loadOptions) {
+	sel, err := RunBubbleteaTUI(inputs...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
 
-	// Use new Bubbletea TUI
-	if err := RunBubbleteaTUI(jp, fileName); err != nil {
-		fmt.Println("Error running TUI:", err)
-		os.Exit(1)
+	// Print the node picked with enter
+	if sel != nil {
+		if err := printSelection(os.Stdout, sel, printMode, opts); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
 	}
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// recordPreviewWidth is the maximum width of a record preview in the record list
+const recordPreviewWidth = 40
+
+// isJSONLinesFile reports whether the file extension marks JSON Lines / NDJSON input
+func isJSONLinesFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return true
+	}
+	return false
+}
+
+// splitRecords splits the JSON data into one processor per JSON Lines record
+// records may span several lines, blank lines between them are ignored
+func (jp *JSONProcessor) splitRecords() error {
+	dec := json.NewDecoder(bytes.NewReader(jp.jsonData))
+	jp.records = nil
+	for {
+		var record json.RawMessage
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			err = newParseError(jp.jsonData, syntaxErr.Offset, syntaxErr.Error())
+		}
+		if err != nil {
+			return fmt.Errorf("record %d: %w", len(jp.records)+1, err)
+		}
+		base := int(dec.InputOffset()) - len(record)
+		jp.records = append(jp.records, &JSONProcessor{jsonData: record, base: base})
+	}
+	return nil
+}
+
+// extractRecordKeys extracts the keys of every record and merges them into the union key set
+func (jp *JSONProcessor) extractRecordKeys() {
+	seenKeys := make(map[string]struct{})
+	jp.keys = nil
+	for _, record := range jp.records {
+		record.extractKeys()
+		for _, key := range record.keys {
+			jp.addKey(key, seenKeys)
+		}
+	}
+}
+
+// query resolves a query against the document, or against every record for JSON Lines input
+// ok is false when no document or record matches
+func (jp *JSONProcessor) query(query string) (results []gjson.Result, ok bool) {
+	if len(jp.records) == 0 {
+		return queryResults(query, jp.jsonData)
+	}
+	for _, record := range jp.records {
+		if recordResults, recordOK := queryResults(query, record.jsonData); recordOK {
+			results = append(results, recordResults...)
+			ok = true
+		}
+	}
+	return results, ok
+}
+
+// getRecordResults renders the value of a key in every record, labelled with the record index
+func getRecordResults(query string, records []*JSONProcessor, asYAML bool) string {
+	var values []string
+	for i, record := range records {
+		results, ok := queryResults(query, record.jsonData)
+		if !ok {
+			continue
+		}
+		if asYAML {
+			values = append(values, fmt.Sprintf("# [%d]\n%s", i, record.toYAML(query)))
+			continue
+		}
+		for _, result := range results {
+			values = append(values, fmt.Sprintf("[%d] %s", i, formatResult(result)))
+		}
+	}
+	if len(values) == 0 {
+		return "Query failed. No matching data found."
+	}
+	return strings.Join(values, "\n")
+}
+
+// buildRecordList builds a flat tree with one node per record and a compact preview of it
+func buildRecordList(records []*JSONProcessor) *TreeNode {
+	root := &TreeNode{depth: -1, expanded: true}
+	for i, record := range records {
+		var compact bytes.Buffer
+		preview := string(record.jsonData)
+		if err := json.Compact(&compact, record.jsonData); err == nil {
+			preview = compact.String()
+		}
+		if runes := []rune(preview); len(runes) > recordPreviewWidth {
+			preview = string(runes[:recordPreviewWidth-1]) + "…"
+		}
+		root.children = append(root.children, &TreeNode{
+			path:   jsonPath{{kind: segmentIndex, index: i}},
+			key:    fmt.Sprintf("[%d]", i),
+			name:   fmt.Sprintf("[%d] %s", i, preview),
+			parent: root,
+		})
+	}
+	return root
+}
+
+// recordIndex returns the record index of a record list key such as "[3]"
+func recordIndex(key string) int {
+	var idx int
+	if _, err := fmt.Sscanf(key, "[%d]", &idx); err != nil {
+		return -1
+	}
+	return idx
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+const logLines = `{"level":"info","msg":"start"}
+{"level":"warn","msg":"slow","ms":120}
+
+{"level":"error",
+ "msg":"failed","err":{"code":7}}
+`
+
+func TestSplitRecords(t *testing.T) {
+	jp := &JSONProcessor{jsonData: []byte(logLines)}
+	if err := jp.splitRecords(); err != nil {
+		t.Fatal(err)
+	}
+	var records []string
+	for _, record := range jp.records {
+		records = append(records, string(record.jsonData))
+	}
+	want := []string{`{"level":"info","msg":"start"}`, `{"level":"warn","msg":"slow","ms":120}`, "{\"level\":\"error\",\n \"msg\":\"failed\",\"err\":{\"code\":7}}"}
+	if !slices.Equal(records, want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+
+	jp = &JSONProcessor{jsonData: []byte("{\"a\":1}\n{\"a\":}\n")}
+	if err := jp.splitRecords(); err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("splitRecords error = %v, want one naming record 2", err)
+	}
+}
+
+func TestRecordKeysAndQuery(t *testing.T) {
+	jp := &JSONProcessor{jsonData: []byte(logLines)}
+	if err := jp.splitRecords(); err != nil {
+		t.Fatal(err)
+	}
+	jp.extractKeys()
+	var keys []string
+	for _, key := range jp.keys {
+		keys = append(keys, key.String())
+	}
+	want := []string{"level", "msg", "ms", "err", "err.code"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("union keys = %v, want %v", keys, want)
+	}
+
+	var out bytes.Buffer
+	if err := runQuery(&out, "level", jp, outputOptions{raw: true}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "info\nwarn\nerror\n" {
+		t.Errorf("query over the records printed %q", out.String())
+	}
+	if got := getRecordResults("ms", jp.records, false); got != "[1] 120" {
+		t.Errorf("getRecordResults(ms) = %q, want [1] 120", got)
+	}
+}
+
+func TestRecordNavigator(t *testing.T) {
+	m := newTestModel(t, logLines)
+	if m.recordView != viewRecords || len(m.rows) != 3 {
+		t.Fatalf("JSON Lines open in view %d with %d rows, want the record list", m.recordView, len(m.rows))
+	}
+	if name := m.rows[1].name; name != `[1] {"level":"warn","msg":"slow","ms":120}` {
+		t.Errorf("record list row = %q", name)
+	}
+
+	// enter opens the selected record
+	m = send(m, tea.KeyPressMsg{Code: tea.KeyDown}, tea.KeyPressMsg{Code: tea.KeyEnter})
+	if m.recordView != viewRecord || m.recordIdx != 1 || m.picked != nil {
+		t.Fatalf("enter on a record: view %d, record %d", m.recordView, m.recordIdx)
+	}
+	if got, want := rowKeys(m), []string{"level", "msg", "ms"}; !slices.Equal(got, want) {
+		t.Errorf("record 1 rows = %v, want %v", got, want)
+	}
+
+	// alt+down moves to the next record, and stops at the last one
+	down := tea.KeyPressMsg{Code: tea.KeyDown, Mod: tea.ModAlt}
+	m = send(m, down, down)
+	if m.recordIdx != 2 {
+		t.Errorf("record %d after alt+down, want 2", m.recordIdx)
+	}
+	if got, want := rowKeys(m), []string{"level", "msg", "err", "err.code"}; !slices.Equal(got, want) {
+		t.Errorf("record 2 rows = %v, want %v", got, want)
+	}
+	if sel := m.currentSelection(); string(sel.source.jsonData) != string(m.jp.records[2].jsonData) {
+		t.Errorf("selection applies to %s, want record 2", sel.source.jsonData)
+	}
+
+	// ctrl+l cycles through the record list, the union of keys and the record again
+	ctrlL := ctrlKey('l')
+	for _, want := range []recordView{viewRecords, viewUnion, viewRecord} {
+		m = send(m, ctrlL)
+		if m.recordView != want {
+			t.Fatalf("view %d after ctrl+l, want %d", m.recordView, want)
+		}
+		if want == viewUnion && len(m.rows) != 5 {
+			t.Errorf("union view lists %v", rowKeys(m))
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tidwall/gjson"
+)
+
+// segmentKind tells which step a path segment takes into a document
+type segmentKind int
+
+const (
+	segmentKey   segmentKind = iota // object member, "name"
+	segmentIndex                    // array element, "[0]"
+	segmentEach                     // every array element, "[]"
+	segmentCount                    // array length, "#"
+	segmentRange                    // array elements from index up to end, "[0..999]"
+)
+
+// pathSegment is one step of a path, an object key or an array index
+// a range holds the elements from index up to but not including end
+type pathSegment struct {
+	kind  segmentKind
+	key   string
+	index int
+	end   int
+}
+
+// jsonPath is a structured path into a document
+// it renders to the readable jex syntax with String and to an escaped gjson path with gjson,
+// so that keys containing ".", "[", "*", "?", "#" or spaces resolve correctly
+type jsonPath []pathSegment
+
+// child returns the path of the object member key
+func (p jsonPath) child(key string) jsonPath {
+	return p.with(pathSegment{kind: segmentKey, key: key})
+}
+
+// element returns the path of the array element at index
+func (p jsonPath) element(index int) jsonPath {
+	return p.with(pathSegment{kind: segmentIndex, index: index})
+}
+
+// each returns the path projecting over every array element
+func (p jsonPath) each() jsonPath {
+	return p.with(pathSegment{kind: segmentEach})
+}
+
+// count returns the path of the array length
+func (p jsonPath) count() jsonPath {
+	return p.with(pathSegment{kind: segmentCount})
+}
+
+// elements returns the path projecting over the array elements from start up to but not including end
+func (p jsonPath) elements(start, end int) jsonPath {
+	return p.with(pathSegment{kind: segmentRange, index: start, end: end})
+}
+
+// with appends a segment without sharing the backing array of p
+func (p jsonPath) with(seg pathSegment) jsonPath {
+	out := make(jsonPath, len(p), len(p)+1)
+	copy(out, p)
+	return append(out, seg)
+}
+
+// hasEach reports whether the path projects over array elements
+func (p jsonPath) hasEach() bool {
+	for _, seg := range p {
+		if seg.kind == segmentEach || seg.kind == segmentRange {
+			return true
+		}
+	}
+	return false
+}
+
+// isColumn reports whether the path is a member of every element of an array, e.g. items[].name
+func (p jsonPath) isColumn() bool {
+	n := len(p)
+	return n >= 2 && p[n-1].kind == segmentKey && p[n-2].kind == segmentEach && !p[:n-2].hasEach()
+}
+
+// within reports whether q is p or lies below it, an index lies below the range holding it
+func (p jsonPath) within(q jsonPath) bool {
+	if len(q) < len(p) {
+		return false
+	}
+	for i, seg := range p {
+		if seg == q[i] {
+			continue
+		}
+		if seg.kind != segmentRange || q[i].kind != segmentIndex || q[i].index < seg.index || q[i].index >= seg.end {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the path in the syntax shown in the search bar and accepted by -q
+// e.g. metadata.labels["app.kubernetes.io/name"], items[0].ports[].port or items.#
+func (p jsonPath) String() string {
+	var b strings.Builder
+	for i, seg := range p {
+		switch seg.kind {
+		case segmentKey:
+			if needsQuoting(seg.key) {
+				b.WriteString("[" + strconv.Quote(seg.key) + "]")
+				continue
+			}
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(seg.key)
+		case segmentIndex:
+			fmt.Fprintf(&b, "[%d]", seg.index)
+		case segmentEach:
+			b.WriteString("[]")
+		case segmentRange:
+			fmt.Fprintf(&b, "[%d..%d]", seg.index, seg.end-1)
+		case segmentCount:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteByte('#')
+		}
+	}
+	return b.String()
+}
+
+// gjson renders a path without projections as a gjson lookup with every key escaped
+func (p jsonPath) gjson() string {
+	parts := make([]string, len(p))
+	for i, seg := range p {
+		switch seg.kind {
+		case segmentKey:
+			parts[i] = gjson.Escape(seg.key)
+		case segmentIndex:
+			parts[i] = strconv.Itoa(seg.index)
+		default:
+			parts[i] = "#"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// needsQuoting reports whether a key has to be written as ["key"] to parse back unchanged
+func needsQuoting(key string) bool {
+	if key == "" {
+		return true
+	}
+	return strings.ContainsFunc(key, func(r rune) bool {
+		return strings.ContainsRune(`.[]"\#`, r) || unicode.IsSpace(r) || !unicode.IsPrint(r)
+	})
+}
+
+// parsePath parses the String syntax of a path
+// bare keys may escape special characters with a backslash as in gjson paths,
+// and "#" followed by more segments projects over the elements like "[]"
+func parsePath(s string) (jsonPath, error) {
+	var p jsonPath
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if i+1 < len(s) && s[i+1] == '"' {
+				key, rest, err := unquotePrefix(s[i+1:])
+				if err != nil {
+					return nil, err
+				}
+				if !strings.HasPrefix(rest, "]") {
+					return nil, fmt.Errorf("missing ] after %s", s[:len(s)-len(rest)])
+				}
+				p = append(p, pathSegment{kind: segmentKey, key: key})
+				i = len(s) - len(rest) + 1
+				continue
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in %q", s)
+			}
+			inner := s[i+1 : i+end]
+			if first, last, ok := strings.Cut(inner, ".."); ok {
+				start, err1 := strconv.Atoi(first)
+				stop, err2 := strconv.Atoi(last)
+				if err1 != nil || err2 != nil || start < 0 || stop < start {
+					return nil, fmt.Errorf("invalid array range %q", inner)
+				}
+				p = append(p, pathSegment{kind: segmentRange, index: start, end: stop + 1})
+			} else if inner == "" {
+				p = append(p, pathSegment{kind: segmentEach})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid array index %q", inner)
+				}
+				p = append(p, pathSegment{kind: segmentIndex, index: index})
+			}
+			i += end + 1
+
+		case s[i] == '.' && i == 0:
+			return nil, errors.New("path starts with '.'")
+
+		default:
+			if s[i] == '.' {
+				i++
+			}
+			var key strings.Builder
+			escaped := false
+			for ; i < len(s); i++ {
+				c := s[i]
+				if !escaped && (c == '.' || c == '[') {
+					break
+				}
+				if !escaped && c == '\\' {
+					escaped = true
+					continue
+				}
+				escaped = false
+				key.WriteByte(c)
+			}
+			if key.Len() == 0 {
+				return nil, fmt.Errorf("empty key in %q", s)
+			}
+			if raw := key.String(); raw == "#" && !strings.HasSuffix(s[:i], `\#`) {
+				p = append(p, pathSegment{kind: segmentCount})
+			} else {
+				p = append(p, pathSegment{kind: segmentKey, key: raw})
+			}
+		}
+	}
+
+	// gjson style "items.#.name" maps over the elements
+	for i := 0; i < len(p)-1; i++ {
+		if p[i].kind == segmentCount {
+			p[i].kind = segmentEach
+		}
+	}
+	return p, nil
+}
+
+// unquotePrefix reads the double-quoted string at the start of s and returns the rest of s
+func unquotePrefix(s string) (string, string, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			key, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid quoted key %s", s[:i+1])
+			}
+			return key, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted key %s", s)
+}
+
+// resolve evaluates the path against a document
+// segments up to a projection are looked up with a single gjson query; after a projection,
+// elements without the remaining path yield a missing result so that rows stay aligned
+func (p jsonPath) resolve(jsonData []byte) ([]gjson.Result, bool) {
+	var results []gjson.Result
+	if n := p.lookupLen(); n > 0 {
+		// the first lookup reads the document in place, parsing it as a whole would copy it
+		if value := gjson.GetBytes(jsonData, p[:n].gjson()); value.Exists() {
+			results = append(results, value)
+		}
+		p = p[n:]
+	} else {
+		results = append(results, gjson.ParseBytes(jsonData))
+	}
+	projected := false
+	for len(p) > 0 {
+		n := p.lookupLen()
+		if n > 0 {
+			lookup := p[:n].gjson()
+			var next []gjson.Result
+			for _, result := range results {
+				if value := result.Get(lookup); value.Exists() || projected {
+					next = append(next, value)
+				}
+			}
+			results = next
+		}
+		if n == len(p) {
+			break
+		}
+
+		var next []gjson.Result
+		seg := p[n]
+		for _, result := range results {
+			if !result.IsArray() {
+				continue
+			}
+			result.ForEach(func(index, value gjson.Result) bool {
+				if seg.kind == segmentRange {
+					if i := int(index.Int()); i < seg.index {
+						return true
+					} else if i >= seg.end {
+						return false
+					}
+				}
+				next = append(next, value)
+				return true
+			})
+		}
+		results = next
+		projected = true
+		p = p[n+1:]
+	}
+	return results, len(results) > 0
+}
+
+// lookupLen returns the number of segments before the first projection
+func (p jsonPath) lookupLen() int {
+	n := 0
+	for n < len(p) && p[n].kind != segmentEach && p[n].kind != segmentRange {
+		n++
+	}
+	return n
+}
+
+// jq renders the path as a jq filter, e.g. .metadata.labels["app.kubernetes.io/name"]
+func (p jsonPath) jq() string {
+	var b strings.Builder
+	for i, seg := range p {
+		switch seg.kind {
+		case segmentKey:
+			switch {
+			case isIdentifier(seg.key):
+				b.WriteString("." + seg.key)
+			case i == 0:
+				b.WriteString(".[" + strconv.Quote(seg.key) + "]")
+			default:
+				b.WriteString("[" + strconv.Quote(seg.key) + "]")
+			}
+		case segmentIndex:
+			if i == 0 {
+				b.WriteByte('.')
+			}
+			fmt.Fprintf(&b, "[%d]", seg.index)
+		case segmentEach:
+			if i == 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString("[]")
+		case segmentRange:
+			if i == 0 {
+				b.WriteByte('.')
+			}
+			fmt.Fprintf(&b, "[%d:%d][]", seg.index, seg.end)
+		case segmentCount:
+			if i == 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(" | length")
+		}
+	}
+	if b.Len() == 0 {
+		return "."
+	}
+	return b.String()
+}
+
+// jsonPathQuery renders the path as a JSONPath query (RFC 9535), e.g. $.items[0]['app.kubernetes.io/name']
+func (p jsonPath) jsonPathQuery() (string, error) {
+	var b strings.Builder
+	b.WriteByte('$')
+	for _, seg := range p {
+		switch seg.kind {
+		case segmentKey:
+			if isIdentifier(seg.key) {
+				b.WriteString("." + seg.key)
+				continue
+			}
+			escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(seg.key)
+			b.WriteString("['" + escaped + "']")
+		case segmentIndex:
+			fmt.Fprintf(&b, "[%d]", seg.index)
+		case segmentEach:
+			b.WriteString("[*]")
+		case segmentRange:
+			fmt.Fprintf(&b, "[%d:%d]", seg.index, seg.end)
+		case segmentCount:
+			return "", errors.New("JSONPath cannot express an array length")
+		}
+	}
+	return b.String(), nil
+}
+
+// pointer renders the path as a JSON Pointer (RFC 6901), e.g. /metadata/labels/app.kubernetes.io~1name
+func (p jsonPath) pointer() (string, error) {
+	var b strings.Builder
+	escape := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, seg := range p {
+		switch seg.kind {
+		case segmentKey:
+			b.WriteString("/" + escape.Replace(seg.key))
+		case segmentIndex:
+			fmt.Fprintf(&b, "/%d", seg.index)
+		default:
+			return "", errors.New("a JSON Pointer can only point at a single value")
+		}
+	}
+	return b.String(), nil
+}
+
+// isIdentifier reports whether a key can be written without quotes in jq and JSONPath,
+// jq only accepts ASCII identifiers of the form [A-Za-z_][A-Za-z0-9_]*
+func isIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		letter := r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+		if !letter && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestPathStringRoundTrip(t *testing.T) {
+	var root jsonPath
+	tests := []struct {
+		path jsonPath
+		want string
+	}{
+		{root.child("name"), "name"},
+		{root.child("company").child("departments").element(0).child("name"), "company.departments[0].name"},
+		{root.child("metadata").child("labels").child("app.kubernetes.io/name"), `metadata.labels["app.kubernetes.io/name"]`},
+		{root.child("items").each().child("port"), "items[].port"},
+		{root.child("items").count(), "items.#"},
+		{root.child("items").elements(100, 200).child("id"), "items[100..199].id"},
+		{root.element(2), "[2]"},
+		{root.child(""), `[""]`},
+		{root.child("with space").child(`quote"d`), `["with space"]["quote\"d"]`},
+		{root.child("a[0]").child("#"), `["a[0]"]["#"]`},
+		{root.child("日本語").child("キー"), "日本語.キー"},
+		{root.child("tab\there"), `["tab\there"]`},
+	}
+	for _, tt := range tests {
+		if got := tt.path.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+		parsed, err := parsePath(tt.want)
+		if err != nil {
+			t.Errorf("parsePath(%s): %v", tt.want, err)
+			continue
+		}
+		if !slices.Equal(parsed, tt.path) {
+			t.Errorf("parsePath(%s) = %#v, want %#v", tt.want, parsed, tt.path)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	var root jsonPath
+	tests := []struct {
+		in   string
+		want jsonPath
+	}{
+		// gjson style escapes and projections are accepted as well
+		{`metadata.labels.app\.kubernetes\.io/name`, root.child("metadata").child("labels").child("app.kubernetes.io/name")},
+		{"items.#.name", root.child("items").each().child("name")},
+		{`items.\#`, root.child("items").child("#")},
+		{"a[1][2]", root.child("a").element(1).element(2)},
+		{"a[3..3]", root.child("a").elements(3, 4)},
+	}
+	for _, tt := range tests {
+		got, err := parsePath(tt.in)
+		if err != nil {
+			t.Errorf("parsePath(%s): %v", tt.in, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parsePath(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{".a", "a..b", "a[", "a[x]", "a[-1]", "a[5..2]", `a["b`, `a["b"`} {
+		if p, err := parsePath(in); err == nil {
+			t.Errorf("parsePath(%s) = %s, want an error", in, p)
+		}
+	}
+}
+
+func TestPathGjson(t *testing.T) {
+	doc := []byte(`{"a.b":{"c*":[{"d?":1},{"e f":2}]},"#":{"x|y":3},"list":[10,20]}`)
+	var root jsonPath
+	tests := []struct {
+		path jsonPath
+		want string
+	}{
+		{root.child("a.b").child("c*").element(0).child("d?"), "1"},
+		{root.child("a.b").child("c*").element(1).child("e f"), "2"},
+		{root.child("#").child("x|y"), "3"},
+		{root.child("list").element(1), "20"},
+		{root.child("list").count(), "2"},
+	}
+	for _, tt := range tests {
+		if got := gjson.GetBytes(doc, tt.path.gjson()); got.Raw != tt.want {
+			t.Errorf("%s: gjson(%s) = %q, want %s", tt.path, tt.path.gjson(), got.Raw, tt.want)
+		}
+	}
+}
+
+func TestPathResolve(t *testing.T) {
+	doc := []byte(`{"items":[{"port":80},{"name":"x"},{"port":443}],"meta":{"a.b":true}}`)
+	tests := []struct {
+		in   string
+		want []string
+		ok   bool
+	}{
+		{`meta["a.b"]`, []string{"true"}, true},
+		{"items[2].port", []string{"443"}, true},
+		// projected rows stay aligned with the elements, missing values included
+		{"items[].port", []string{"80", "", "443"}, true},
+		{"items[1..2].port", []string{"", "443"}, true},
+		{"missing", nil, false},
+		{"meta[].x", nil, false},
+	}
+	for _, tt := range tests {
+		p, err := parsePath(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, ok := p.resolve(doc)
+		var got []string
+		for _, result := range results {
+			got = append(got, result.Raw)
+		}
+		if ok != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("resolve(%s) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPathFormats(t *testing.T) {
+	var root jsonPath
+	tests := []struct {
+		path             jsonPath
+		jq, query, point string
+	}{
+		{root, ".", "$", ""},
+		{root.child("metadata").child("labels").child("app.kubernetes.io/name"),
+			`.metadata.labels["app.kubernetes.io/name"]`, `$.metadata.labels['app.kubernetes.io/name']`, "/metadata/labels/app.kubernetes.io~1name"},
+		{root.child("items").element(0).child("_id2"), ".items[0]._id2", "$.items[0]._id2", "/items/0/_id2"},
+		{root.element(1).child("2x"), `.[1]["2x"]`, `$[1]['2x']`, "/1/2x"},
+		{root.child("it's").child(`a\b`), `.["it's"]["a\\b"]`, `$['it\'s']['a\\b']`, `/it's/a\b`},
+		{root.child("~tilde"), `.["~tilde"]`, `$['~tilde']`, "/~0tilde"},
+		{root.child(""), `.[""]`, `$['']`, "/"},
+		// jq identifiers are ASCII only
+		{root.child("名前").child("é"), `.["名前"]["é"]`, `$['名前']['é']`, "/名前/é"},
+		{root.child("a-b").child("Z9"), `.["a-b"].Z9`, `$['a-b'].Z9`, "/a-b/Z9"},
+	}
+	for _, tt := range tests {
+		if got := tt.path.jq(); got != tt.jq {
+			t.Errorf("%s: jq() = %s, want %s", tt.path, got, tt.jq)
+		}
+		if got, err := tt.path.jsonPathQuery(); err != nil || got != tt.query {
+			t.Errorf("%s: jsonPathQuery() = %s, %v, want %s", tt.path, got, err, tt.query)
+		}
+		if got, err := tt.path.pointer(); err != nil || got != tt.point {
+			t.Errorf("%s: pointer() = %s, %v, want %s", tt.path, got, err, tt.point)
+		}
+	}
+
+	// projections and lengths have no JSON Pointer, lengths no JSONPath either
+	if got := root.child("items").each().child("id").jq(); got != ".items[].id" {
+		t.Errorf("jq() of a projection = %s", got)
+	}
+	if got := root.child("items").count().jq(); got != ".items | length" {
+		t.Errorf("jq() of a length = %s", got)
+	}
+	if got, err := root.child("items").each().jsonPathQuery(); err != nil || got != "$.items[*]" {
+		t.Errorf("jsonPathQuery() of a projection = %s, %v", got, err)
+	}
+	if got := root.child("items").elements(0, 1000).jq(); got != ".items[0:1000][]" {
+		t.Errorf("jq() of a range = %s", got)
+	}
+	if got, err := root.child("items").elements(0, 1000).jsonPathQuery(); err != nil || got != "$.items[0:1000]" {
+		t.Errorf("jsonPathQuery() of a range = %s, %v", got, err)
+	}
+	if _, err := root.child("items").count().jsonPathQuery(); err == nil {
+		t.Error("jsonPathQuery() of a length succeeded")
+	}
+	if _, err := root.child("items").each().pointer(); err == nil {
+		t.Error("pointer() of a projection succeeded")
+	}
+}
+
+func TestPathWithin(t *testing.T) {
+	var root jsonPath
+	bucket := root.child("items").elements(1000, 2000)
+	tests := []struct {
+		p, q jsonPath
+		want bool
+	}{
+		{bucket, bucket, true},
+		{bucket, root.child("items").element(1000).child("id"), true},
+		{bucket, root.child("items").element(1999), true},
+		{bucket, root.child("items").element(2000), false},
+		{bucket, root.child("items").element(999), false},
+		{bucket, root.child("items"), false},
+		{root.child("items"), root.child("items").element(5), true},
+		{root.child("a"), root.child("b").child("c"), false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.within(tt.q); got != tt.want {
+			t.Errorf("%s.within(%s) = %v, want %v", tt.p, tt.q, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// repair is a change made to invalid input to recover a document from it
+type repair struct {
+	offset    int // byte offset in the input
+	line, col int // 1-based position of offset, filled in by JSONProcessor.recover
+	note      string
+}
+
+func (r repair) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", r.line, r.col, r.note)
+}
+
+// recover replaces invalid input with the document recoverJSON finds in it, keeping the input
+// parseErr is the error of the input and is returned when nothing can be recovered
+func (jp *JSONProcessor) recover(parseErr error, jsonLines bool) error {
+	original := jp.jsonData
+	data, repairs, srcMap := recoverJSON(original, jsonLines)
+	jp.jsonData = data
+	if err := jp.parse(jsonLines); err != nil {
+		jp.jsonData = original
+		jp.records = nil
+		return parseErr
+	}
+
+	// repairs are mostly in input order, so the positions are found in a single pass
+	sort.SliceStable(repairs, func(i, j int) bool { return repairs[i].offset < repairs[j].offset })
+	line, lineStart, pos := 1, 0, 0
+	for i := range repairs {
+		offset := min(repairs[i].offset, len(original))
+		for ; pos < offset; pos++ {
+			if original[pos] == '\n' {
+				line++
+				lineStart = pos + 1
+			}
+		}
+		repairs[i].line = line
+		repairs[i].col = utf8.RuneCount(original[lineStart:offset]) + 1
+	}
+	jp.parseErr = parseErr
+	jp.repairs = repairs
+	jp.source = original
+	jp.sourceMap = srcMap
+	return nil
+}
+
+// recoverySummary describes the repairs made to open invalid input, empty when there were none
+func (jp *JSONProcessor) recoverySummary() string {
+	switch len(jp.repairs) {
+	case 0:
+		return ""
+	case 1:
+		return "recovered from invalid JSON, 1 repair at " + jp.repairs[0].String()
+	}
+	return fmt.Sprintf("recovered from invalid JSON, %d repairs, the first at %s", len(jp.repairs), jp.repairs[0])
+}
+
+// recoverExpect is what the recovering scanner expects next
+type recoverExpect int
+
+const (
+	expectValue recoverExpect = iota // a value, or the end of an array
+	expectKey                        // an object key, or the end of the object
+	expectColon                      // the colon after a key
+	expectComma                      // a comma or the end of the container
+	expectEnd                        // nothing, the document is complete
+)
+
+// recoverer rewrites invalid JSON into the closest valid document it can find
+// the whitespace of the input is kept so that line numbers stay close to the original
+type recoverer struct {
+	src     []byte
+	pos     int
+	out     bytes.Buffer
+	stack   []byte // open containers, '{' or '['
+	expect  recoverExpect
+	comma   int // position of the last comma in out, -1 when a value followed it
+	repairs []repair
+
+	// stream reads a sequence of documents, as in JSON Lines, docs counts the ones started
+	stream bool
+	docs   int
+
+	// token is the input offset of the token being read, srcMap maps the output back to the input
+	token  int
+	srcMap sourceMap
+}
+
+// recoverJSON returns a best-effort valid document for invalid input, the repairs it made and a map
+// from the document back to the input
+// it fixes comments, trailing and missing commas, single quotes, unquoted keys and strings,
+// NaN and Infinity, unterminated strings and unclosed containers, and drops what cannot be read;
+// in a stream of documents a '{' or '[' at the start of a line begins a new document, closing
+// what the previous one left open
+func recoverJSON(data []byte, stream bool) ([]byte, []repair, *sourceMap) {
+	r := &recoverer{src: data, comma: -1, stream: stream}
+	r.out.Grow(len(data))
+	for r.pos < len(r.src) {
+		r.step()
+	}
+	r.token = len(r.src)
+	r.finish()
+	return r.out.Bytes(), r.repairs, &r.srcMap
+}
+
+// fix records a repair at the current position
+func (r *recoverer) fix(note string, args ...any) {
+	r.repairs = append(r.repairs, repair{offset: r.pos, note: fmt.Sprintf(note, args...)})
+}
+
+// step reads the next token of the input
+func (r *recoverer) step() {
+	c := r.src[r.pos]
+	r.token = r.pos
+	switch {
+	case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		r.out.WriteByte(c)
+		r.pos++
+	case r.stream && (c == '{' || c == '[') && len(r.stack) > 0 && (r.pos == 0 || r.src[r.pos-1] == '\n'):
+		for len(r.stack) > 0 {
+			r.fix("closed %s left open", containerName(r.stack[len(r.stack)-1]))
+			r.endContainer()
+		}
+	case r.expect == expectEnd && r.stream:
+		r.expect = expectValue
+	case r.expect == expectEnd:
+		r.fix("ignored the text after the document")
+		r.pos = len(r.src)
+	case c == '/' && r.pos+1 < len(r.src) && (r.src[r.pos+1] == '/' || r.src[r.pos+1] == '*'):
+		r.skipComment()
+	case c == '{' || c == '[':
+		r.beforeValue()
+		r.mark()
+		r.out.WriteByte(c)
+		r.stack = append(r.stack, c)
+		r.expect = expectValue
+		if c == '{' {
+			r.expect = expectKey
+		}
+		r.pos++
+	case c == '}' || c == ']':
+		r.close(c)
+	case c == ',':
+		r.readComma()
+	case c == ':':
+		if r.expect == expectColon {
+			r.out.WriteByte(':')
+			r.expect = expectValue
+		} else {
+			r.fix("removed an unexpected ':'")
+		}
+		r.pos++
+	case c == '"' || c == '\'':
+		r.readString(c)
+	case c == '-' || c == '+' || c == '.' || c >= '0' && c <= '9':
+		r.readNumber()
+	case isIdentStart(c):
+		r.readWord()
+	default:
+		_, size := utf8.DecodeRune(r.src[r.pos:])
+		r.fix("removed the unexpected character %q", r.src[r.pos:r.pos+size])
+		r.pos += size
+	}
+}
+
+// skipComment drops a // or /* */ comment
+func (r *recoverer) skipComment() {
+	r.fix("removed a comment")
+	if r.src[r.pos+1] == '/' {
+		end := bytes.IndexByte(r.src[r.pos:], '\n')
+		if end < 0 {
+			end = len(r.src) - r.pos
+		}
+		r.pos += end
+		return
+	}
+	end := bytes.Index(r.src[r.pos+2:], []byte("*/"))
+	if end < 0 {
+		r.pos = len(r.src)
+		return
+	}
+	// keep the line breaks of block comments
+	r.out.Write(bytes.Repeat([]byte("\n"), bytes.Count(r.src[r.pos:r.pos+2+end], []byte("\n"))))
+	r.pos += end + 4
+}
+
+// mark records that the output written next comes from the current token
+func (r *recoverer) mark() {
+	r.srcMap.add(r.out.Len(), r.token)
+}
+
+// beforeValue makes room for a value: a missing comma, colon or key is added
+func (r *recoverer) beforeValue() {
+	if r.expect == expectComma {
+		r.fix("added a missing comma")
+		r.out.WriteByte(',')
+		r.expect = expectValue
+		if r.stack[len(r.stack)-1] == '{' {
+			r.expect = expectKey
+		}
+	}
+	switch r.expect {
+	case expectKey:
+		r.fix("added a missing key")
+		r.out.WriteString(`"":`)
+	case expectColon:
+		r.fix("added a missing colon")
+		r.out.WriteByte(':')
+	}
+	r.comma = -1
+	if len(r.stack) == 0 {
+		r.docs++
+	}
+}
+
+// afterValue moves on after a complete value
+func (r *recoverer) afterValue() {
+	r.expect = expectComma
+	if len(r.stack) == 0 {
+		r.expect = expectEnd
+	}
+}
+
+// writeValue writes a scalar value, or a key when a key is expected and key is true
+func (r *recoverer) writeValue(raw []byte, key bool) {
+	if key && (r.expect == expectKey || r.expect == expectComma && r.stack[len(r.stack)-1] == '{') {
+		if r.expect == expectComma {
+			r.fix("added a missing comma")
+			r.out.WriteByte(',')
+		}
+		r.mark()
+		r.out.Write(raw)
+		r.srcMap.end(r.out.Len(), r.pos)
+		r.expect = expectColon
+		r.comma = -1
+		return
+	}
+	r.beforeValue()
+	r.mark()
+	r.out.Write(raw)
+	// rewritten values differ in length from the input, e.g. 'a' and "a" or NaN and null
+	r.srcMap.end(r.out.Len(), r.pos)
+	r.afterValue()
+}
+
+// close ends the innermost container, closing the containers left open inside it
+func (r *recoverer) close(c byte) {
+	open := byte('{')
+	if c == ']' {
+		open = '['
+	}
+	if bytes.LastIndexByte(r.stack, open) < 0 {
+		r.fix("removed an unexpected '%c'", c)
+		r.pos++
+		return
+	}
+	for {
+		top := r.stack[len(r.stack)-1]
+		r.endContainer()
+		if top == open {
+			break
+		}
+		r.fix("closed %s left open", containerName(top))
+	}
+	r.pos++
+}
+
+// endContainer writes the end of the innermost container
+func (r *recoverer) endContainer() {
+	switch r.expect {
+	case expectColon:
+		r.fix("added a missing value")
+		r.out.WriteString(":null")
+	case expectValue, expectKey:
+		if r.comma >= 0 {
+			r.fix("removed a trailing comma")
+			r.out.Bytes()[r.comma] = ' '
+		} else if r.expect == expectValue && r.stack[len(r.stack)-1] == '{' {
+			r.fix("added a missing value")
+			r.out.WriteString("null")
+		}
+	}
+	r.comma = -1
+	top := r.stack[len(r.stack)-1]
+	r.stack = r.stack[:len(r.stack)-1]
+	r.mark()
+	if top == '{' {
+		r.out.WriteByte('}')
+	} else {
+		r.out.WriteByte(']')
+	}
+	r.afterValue()
+}
+
+// containerName names a container for the repair notes
+func containerName(open byte) string {
+	if open == '{' {
+		return "an object"
+	}
+	return "an array"
+}
+
+// readComma writes a comma between values and drops misplaced ones
+func (r *recoverer) readComma() {
+	switch r.expect {
+	case expectComma:
+		if len(r.stack) > 0 {
+			r.comma = r.out.Len()
+			r.out.WriteByte(',')
+			r.expect = expectValue
+			if r.stack[len(r.stack)-1] == '{' {
+				r.expect = expectKey
+			}
+		}
+	case expectColon:
+		r.fix("added a missing value")
+		r.out.WriteString(":null")
+		r.comma = r.out.Len()
+		r.out.WriteByte(',')
+		r.expect = expectKey
+	default:
+		r.fix("removed an extra comma")
+	}
+	r.pos++
+}
+
+// readString reads a string quoted with double or single quotes and writes it as a JSON string
+// a string still open at the end of the line is closed there
+func (r *recoverer) readString(quote byte) {
+	if quote == '\'' {
+		r.fix("replaced single quotes with double quotes")
+	}
+	start := r.pos
+	r.pos++
+	var s bytes.Buffer
+	s.WriteByte('"')
+	for {
+		if r.pos >= len(r.src) || r.src[r.pos] == '\n' {
+			r.repairs = append(r.repairs, repair{offset: start, note: "closed a string left open"})
+			break
+		}
+		c := r.src[r.pos]
+		if c == quote {
+			r.pos++
+			break
+		}
+		switch {
+		case c == '\\' && r.pos+1 < len(r.src):
+			next := r.src[r.pos+1]
+			switch {
+			case next == '\'':
+				s.WriteByte('\'')
+			case next == 'u' && r.pos+6 <= len(r.src) && isHex(r.src[r.pos+2:r.pos+6]):
+				s.Write(r.src[r.pos : r.pos+6])
+				r.pos += 4
+			case bytes.IndexByte([]byte(`"\/bfnrt`), next) >= 0:
+				s.Write(r.src[r.pos : r.pos+2])
+			default:
+				r.fix("escaped a backslash")
+				s.WriteString(`\\`)
+				r.pos--
+			}
+			r.pos += 2
+			continue
+		case c == '"':
+			s.WriteString(`\"`)
+		case c < ' ':
+			fmt.Fprintf(&s, `\u%04x`, c)
+		default:
+			s.WriteByte(c)
+		}
+		r.pos++
+	}
+	s.WriteByte('"')
+	r.writeValue(s.Bytes(), true)
+}
+
+// isHex reports whether b holds only hexadecimal digits
+func isHex(b []byte) bool {
+	for _, c := range b {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// readNumber reads a number, rewriting forms JSON does not accept such as +1, .5, 1. or 0x10
+func (r *recoverer) readNumber() {
+	start := r.pos
+	for r.pos < len(r.src) && (isIdentPart(r.src[r.pos]) || bytes.IndexByte([]byte("+-."), r.src[r.pos]) >= 0) {
+		// a sign only continues a number after an exponent
+		if c := r.src[r.pos]; (c == '+' || c == '-') && r.pos > start && r.src[r.pos-1] != 'e' && r.src[r.pos-1] != 'E' {
+			break
+		}
+		r.pos++
+	}
+	token := r.src[start:r.pos]
+	raw := token
+	if !json.Valid(token) {
+		raw = []byte("null")
+		if v, err := strconv.ParseFloat(string(token), 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			raw = strconv.AppendFloat(nil, v, 'g', -1, 64)
+		} else if v, err := strconv.ParseInt(string(token), 0, 64); err == nil {
+			raw = strconv.AppendInt(nil, v, 10)
+		}
+		r.repairs = append(r.repairs, repair{offset: start, note: fmt.Sprintf("replaced the number %s with %s", token, raw)})
+	}
+	if r.expect == expectKey {
+		key, _ := json.Marshal(string(token))
+		r.repairs = append(r.repairs, repair{offset: start, note: "quoted a key"})
+		r.writeValue(key, true)
+		return
+	}
+	r.writeValue(raw, false)
+}
+
+// readWord reads an unquoted word: a literal, NaN, Infinity or undefined, or an unquoted key or string
+func (r *recoverer) readWord() {
+	start := r.pos
+	for r.pos < len(r.src) && isIdentPart(r.src[r.pos]) {
+		r.pos++
+	}
+	word := string(r.src[start:r.pos])
+	quoted, _ := json.Marshal(word)
+	if r.expect == expectKey || r.expect == expectComma && len(r.stack) > 0 && r.stack[len(r.stack)-1] == '{' {
+		r.repairs = append(r.repairs, repair{offset: start, note: "quoted the key " + word})
+		r.writeValue(quoted, true)
+		return
+	}
+	switch word {
+	case "true", "false", "null":
+		r.writeValue([]byte(word), false)
+	case "NaN", "Infinity", "undefined":
+		r.repairs = append(r.repairs, repair{offset: start, note: "replaced " + word + " with null"})
+		r.writeValue([]byte("null"), false)
+	default:
+		r.repairs = append(r.repairs, repair{offset: start, note: "quoted the string " + word})
+		r.writeValue(quoted, false)
+	}
+}
+
+// finish closes what the input left open
+func (r *recoverer) finish() {
+	switch {
+	case r.expect == expectEnd, r.stream && r.docs > 0 && len(r.stack) == 0:
+		return
+	case len(r.stack) == 0:
+		r.fix("added a missing value")
+		r.out.WriteString("null")
+		return
+	}
+	for len(r.stack) > 0 {
+		r.fix("closed %s left open", containerName(r.stack[len(r.stack)-1]))
+		r.endContainer()
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRecoverJSON(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		notes []string
+	}{
+		{`{"a":1,}`, `{"a":1 }`, []string{"removed a trailing comma"}},
+		{`[1,,2]`, `[1,2]`, []string{"removed an extra comma"}},
+		{`{"a":1 "b":2}`, `{"a":1 ,"b":2}`, []string{"added a missing comma"}},
+		{`{"a" 1}`, `{"a" :1}`, []string{"added a missing colon"}},
+		{`{"a":}`, `{"a":null}`, []string{"added a missing value"}},
+		{`{a: 'x', "b": NaN}`, `{"a": "x", "b": null}`, []string{"quoted the key a", "replaced single quotes with double quotes", "replaced NaN with null"}},
+		{`{"a":undefined}`, `{"a":null}`, []string{"replaced undefined with null"}},
+		{`{"p":"c:\dir"}`, `{"p":"c:\\dir"}`, []string{"escaped a backslash"}},
+		{"// c\n{\"a\":/* x */1}", "\n{\"a\":1}", []string{"removed a comment", "removed a comment"}},
+		{`[1, 2`, `[1, 2]`, []string{"closed an array left open"}},
+		{`{"a": "open`, `{"a": "open"}`, []string{"closed a string left open", "closed an object left open"}},
+		{`[1}`, `[1]`, []string{"removed an unexpected '}'", "closed an array left open"}},
+		{`{"a":1} trailing`, `{"a":1} `, []string{"ignored the text after the document"}},
+		{`@`, `null`, []string{`removed the unexpected character "@"`, "added a missing value"}},
+	}
+	for _, tt := range tests {
+		out, repairs, _ := recoverJSON([]byte(tt.in), false)
+		if string(out) != tt.want {
+			t.Errorf("recoverJSON(%s) = %s, want %s", tt.in, out, tt.want)
+		}
+		if !json.Valid(out) {
+			t.Errorf("recoverJSON(%s) = %s, which is not valid JSON", tt.in, out)
+		}
+		var notes []string
+		for _, r := range repairs {
+			notes = append(notes, r.note)
+		}
+		if !slices.Equal(notes, tt.notes) {
+			t.Errorf("recoverJSON(%s) repairs = %q, want %q", tt.in, notes, tt.notes)
+		}
+	}
+}
+
+func TestRecoverJSONStream(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// a '{' at the start of a line begins the next record
+		{"{\"a\":1\n{\"b\":2}", "{\"a\":1\n}{\"b\":2}"},
+		{"{\"a\":1}\n[1,\n", "{\"a\":1}\n[1 \n]"},
+	}
+	for _, tt := range tests {
+		if out, _, _ := recoverJSON([]byte(tt.in), true); string(out) != tt.want {
+			t.Errorf("recoverJSON(%q) = %q, want %q", tt.in, out, tt.want)
+		}
+	}
+
+	// as a single document the same input stays one object
+	if out, _, _ := recoverJSON([]byte("{\"a\":1\n{\"b\":2}"), false); !json.Valid(out) {
+		t.Errorf("recoverJSON = %q, which is not valid JSON", out)
+	}
+}
+
+func TestLoadRecovered(t *testing.T) {
+	path := writeInput(t, "bad.json", "{\n  \"a\": 1,\n  \"b\": [1, 2,],\n}\n")
+	_, err := loadInput(loadOptions{path: path}, nil)
+	if err == nil || !strings.Contains(err.Error(), "line 3, column 14") || !strings.HasSuffix(err.Error(), "start jex with -recover to open the valid parts") {
+		t.Errorf("error = %v", err)
+	}
+
+	// -recover only repairs plain JSON, a JSON5 file does not suggest it
+	_, err = loadInput(loadOptions{path: writeInput(t, "bad.json5", "{a: [1 2]}")}, nil)
+	if err == nil || strings.Contains(err.Error(), "-recover") {
+		t.Errorf("JSON5 error = %v", err)
+	}
+
+	jp, err := loadInput(loadOptions{path: path, recover: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jp.parseErr == nil || !json.Valid(jp.jsonData) {
+		t.Errorf("recovered %q, parse error %v", jp.jsonData, jp.parseErr)
+	}
+	want := []string{"line 3, column 14: removed a trailing comma", "line 4, column 1: removed a trailing comma"}
+	var got []string
+	for _, r := range jp.repairs {
+		got = append(got, r.String())
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("repairs = %q, want %q", got, want)
+	}
+	jp.extractKeys()
+	m := NewBubbleteaModel(jp, path)
+	if m.status != "recovered from invalid JSON, 2 repairs, the first at "+want[0] {
+		t.Errorf("status %q", m.status)
+	}
+
+	// JSON Lines records are recovered one by one
+	jp, err = loadInput(loadOptions{path: writeInput(t, "bad.jsonl", "{\"a\":1}\n{\"a\":2,}\n{\"a\":3\n"), recover: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jp.records) != 3 || len(jp.repairs) != 2 {
+		t.Errorf("%d records with %d repairs, want 3 with 2", len(jp.records), len(jp.repairs))
+	}
+}
+
+func TestRecoverJSONSourceMap(t *testing.T) {
+	in := `{a: 'x', b: NaN}`
+	out, _, srcMap := recoverJSON([]byte(in), false)
+	for _, tt := range []struct{ out, src string }{{`"a"`, "a"}, {`"x"`, "'x'"}, {"null", "NaN"}} {
+		start := bytes.Index(out, []byte(tt.out))
+		end := start + len(tt.out)
+		if got := in[srcMap.sourceOffset(start):srcMap.sourceEnd(end)]; got != tt.src {
+			t.Errorf("%s maps to %q, want %q", tt.out, got, tt.src)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/tidwall/gjson"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// violationMarker is shown in front of nodes that fail the schema, and of collapsed nodes holding them
+const violationMarker = "✗ "
+
+var (
+	violationStyle       = lipgloss.NewStyle().Foreground(tnRed)
+	nestedViolationStyle = lipgloss.NewStyle().Foreground(tnYellow)
+	violationKeyStyle    = lipgloss.NewStyle().Foreground(tnRed).Bold(true)
+)
+
+// schemaPrinter renders the messages of the validator
+var schemaPrinter = message.NewPrinter(language.English)
+
+// violation is a schema keyword failed by a value of the document
+type violation struct {
+	path    jsonPath // the failing value, empty for the document itself
+	keyword string   // location of the keyword in the schema, e.g. user.json#/properties/age/minimum
+	message string
+}
+
+// loadSchema compiles the JSON Schema in a file
+// the draft is taken from $schema, 2020-12 when it is missing; $ref to other local files is resolved
+func loadSchema(path string) (*jsonschema.Schema, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+	schema, err := c.Compile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// validate checks the document, or each JSON Lines record, against the schema and keeps the violations
+func (jp *JSONProcessor) validate(schema *jsonschema.Schema) error {
+	jp.schema = schema
+	if len(jp.records) > 0 {
+		for _, record := range jp.records {
+			if err := record.validate(schema); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(jp.jsonData))
+	if err != nil {
+		return err
+	}
+	jp.violations = nil
+	if err := schema.Validate(doc); err != nil {
+		verr, ok := err.(*jsonschema.ValidationError)
+		if !ok {
+			return err
+		}
+		jp.collectViolations(verr)
+	}
+	return nil
+}
+
+// collectViolations adds the failed keywords at the leaves of a validation error
+// the inner nodes only summarize their causes, e.g. "allOf failed"
+func (jp *JSONProcessor) collectViolations(err *jsonschema.ValidationError) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			jp.collectViolations(cause)
+		}
+		return
+	}
+	// schemas split over several files are told apart by their file name
+	file, fragment, _ := strings.Cut(err.SchemaURL, "#")
+	keyword := path.Base(file) + "#" + fragment
+	for _, token := range err.ErrorKind.KeywordPath() {
+		keyword += "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+	}
+	jp.violations = append(jp.violations, violation{
+		path:    pointerPath(jp.jsonData, err.InstanceLocation),
+		keyword: keyword,
+		message: err.ErrorKind.LocalizedString(schemaPrinter),
+	})
+}
+
+// pointerPath turns the tokens of a JSON Pointer into a path, tokens select elements of arrays
+func pointerPath(data []byte, tokens []string) jsonPath {
+	var path jsonPath
+	value := gjson.ParseBytes(data)
+	for _, token := range tokens {
+		if index, err := strconv.Atoi(token); err == nil && value.IsArray() {
+			path = path.element(index)
+		} else {
+			path = path.child(token)
+		}
+		value = value.Get(gjson.Escape(token))
+	}
+	return path
+}
+
+// markViolations attaches the violations of a document to the nodes of its tree
+// the ancestors count the violations below them, so that collapsed nodes can show them
+func markViolations(root *TreeNode, violations []violation) {
+	for _, v := range violations {
+		node := root
+		if len(v.path) > 0 {
+			node = root.locate(v.path)
+		}
+		if node == nil {
+			continue
+		}
+		node.violations = append(node.violations, v)
+		for p := node.parent; p != nil; p = p.parent {
+			p.nestedViolations++
+		}
+	}
+}
+
+// errorMarker returns the marker of a node, empty when the node and its collapsed children are valid
+func (n *TreeNode) errorMarker(styled bool) string {
+	style := violationStyle
+	switch {
+	case len(n.violations) > 0:
+	case n.nestedViolations > 0 && !n.expanded:
+		style = nestedViolationStyle
+	default:
+		return ""
+	}
+	if !styled {
+		return violationMarker
+	}
+	return style.Render(violationMarker)
+}
+
+// violationReport lists the violations of a node above its value in the extractor
+func violationReport(violations []violation) string {
+	var b strings.Builder
+	for _, v := range violations {
+		b.WriteString(violationKeyStyle.Render(violationMarker+v.keyword) + "\n")
+		b.WriteString("  " + v.message + "\n")
+	}
+	return b.String() + "\n"
+}
+
+// documentViolations returns the violations of the document in the current view
+func (m *Model) documentViolations() []violation {
+	if m.recordView != viewRecord || m.diff != nil {
+		return nil
+	}
+	return m.source().violations
+}
+
+// schemaSummary returns the status shown when a validated document is opened
+func (m *Model) schemaSummary() string {
+	if m.jp.schema == nil {
+		return ""
+	}
+	count := len(m.jp.violations)
+	for _, record := range m.jp.records {
+		count += len(record.violations)
+	}
+	if count == 0 {
+		return "the input matches the schema"
+	}
+	return fmt.Sprintf("%d schema errors, alt+n / alt+p go to the next / previous one", count)
+}
+
+// nextViolation selects the node of the next violation, or of the previous one for a negative delta
+// violations of the document itself are shown in the status line
+func (m *Model) nextViolation(delta int) {
+	if m.jp.schema == nil {
+		m.status = "no schema, start jex with --schema"
+		return
+	}
+	if m.recordView != viewRecord {
+		m.status = "open a record to go to its errors"
+		return
+	}
+	violations := m.documentViolations()
+	n := len(violations)
+	if n == 0 {
+		m.status = "the document matches the schema"
+		return
+	}
+	// violationIdx counts from 1, 0 until the first error was shown
+	switch {
+	case m.violationIdx > 0:
+		m.violationIdx = ((m.violationIdx-1+delta)%n+n)%n + 1
+	case delta > 0:
+		m.violationIdx = 1
+	default:
+		m.violationIdx = n
+	}
+	v := violations[m.violationIdx-1]
+	if len(v.path) == 0 {
+		m.status = fmt.Sprintf("error %d/%d in the document: %s", m.violationIdx, n, v.message)
+		return
+	}
+	m.status = fmt.Sprintf("error %d/%d at %s: %s", m.violationIdx, n, v.path, v.message)
+	if target := m.root.locate(v.path); target != nil {
+		m.showNode(target)
+	}
+}
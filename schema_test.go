@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+const userSchema = `{
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "user": {"properties": {"age": {"type": "integer", "minimum": 0}}},
+    "tags": {"items": {"type": "string"}},
+    "a/b~c": {"type": "number"}
+  }
+}`
+
+// schemaModel validates data against schema and opens it in a model
+func schemaModel(t *testing.T, schema, data string) Model {
+	t.Helper()
+	s, err := loadSchema(writeInput(t, "schema.json", schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jp := &JSONProcessor{jsonData: []byte(data)}
+	if err := jp.parse(false); err != nil {
+		t.Fatal(err)
+	}
+	jp.extractKeys()
+	if err := jp.validate(s); err != nil {
+		t.Fatal(err)
+	}
+	return NewBubbleteaModel(jp, "test.json")
+}
+
+func TestLoadSchema(t *testing.T) {
+	if _, err := loadSchema(writeInput(t, "ok.json", userSchema)); err != nil {
+		t.Errorf("valid schema: %v", err)
+	}
+
+	// $ref is resolved against other local files
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "age.json"), []byte(`{"type":"integer"}`)); err != nil {
+		t.Fatal(err)
+	}
+	ref := filepath.Join(dir, "ref.json")
+	if err := writeFile(ref, []byte(`{"properties":{"age":{"$ref":"age.json"}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSchema(ref); err != nil {
+		t.Errorf("schema with a $ref: %v", err)
+	}
+
+	for name, schema := range map[string]string{
+		"syntax.json":  `{"type": "object",}`,
+		"keyword.json": `{"type": 5}`,
+		"ref.json":     `{"$ref": "missing.json"}`,
+		"empty.json":   ``,
+	} {
+		_, err := loadSchema(writeInput(t, name, schema))
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: error %v, want one naming the file", name, err)
+		}
+	}
+	if _, err := loadSchema(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("a missing schema file loaded")
+	}
+}
+
+func TestPointerPath(t *testing.T) {
+	data := []byte(`{"items":[{"id":1}],"map":{"0":true},"a/b":{"~x":1},"grid":[[1,{"k":2}]],"~0":{"~1":1},"a.b*":{"c?":1}}`)
+	var root jsonPath
+	tests := []struct {
+		tokens []string
+		want   jsonPath
+	}{
+		{nil, nil},
+		{[]string{"items", "0", "id"}, root.child("items").element(0).child("id")},
+		// numeric keys of objects stay keys
+		{[]string{"map", "0"}, root.child("map").child("0")},
+		// the validator passes unescaped tokens, / and ~ are part of the key
+		{[]string{"a/b", "~x"}, root.child("a/b").child("~x")},
+		// keys that look like escapes are not unescaped a second time
+		{[]string{"~0", "~1"}, root.child("~0").child("~1")},
+		{[]string{"grid", "0", "1", "k"}, root.child("grid").element(0).element(1).child("k")},
+		// gjson syntax in keys is escaped while walking the data
+		{[]string{"a.b*", "c?"}, root.child("a.b*").child("c?")},
+		// an index past the data is still an element
+		{[]string{"items", "5"}, root.child("items").element(5)},
+	}
+	for _, tt := range tests {
+		if got := pointerPath(data, tt.tokens); !slices.Equal(got, tt.want) {
+			t.Errorf("pointerPath(%q) = %s, want %s", tt.tokens, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m := schemaModel(t, userSchema, `{"user":{"age":-1},"tags":["x",2],"a/b~c":"s"}`)
+	got := make(map[string]string)
+	for _, v := range m.jp.violations {
+		got[v.path.String()] = v.keyword
+	}
+	// violations are reported at the failing value, the keyword with ~0 / ~1 escapes
+	want := map[string]string{
+		"":         "schema.json#/required",
+		"user.age": "schema.json#/properties/user/properties/age/minimum",
+		"tags[1]":  "schema.json#/properties/tags/items/type",
+		"a/b~c":    "schema.json#/properties/a~1b~0c/type",
+	}
+	if len(got) != len(want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+	for path, keyword := range want {
+		if got[path] != keyword {
+			t.Errorf("violation at %q = %q, want %q", path, got[path], keyword)
+		}
+	}
+	if !strings.Contains(m.status, "4 schema errors") {
+		t.Errorf("status %q", m.status)
+	}
+
+	// JSON Lines records are validated one by one
+	s, err := loadSchema(writeInput(t, "schema.json", userSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jp := &JSONProcessor{jsonData: []byte("{\"name\":\"a\"}\n{\"user\":{\"age\":-1}}\n")}
+	if err := jp.parse(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := jp.validate(s); err != nil {
+		t.Fatal(err)
+	}
+	if len(jp.records) != 2 || len(jp.records[0].violations) != 0 || len(jp.records[1].violations) != 2 {
+		t.Errorf("record violations = %v / %v", jp.records[0].violations, jp.records[1].violations)
+	}
+
+	valid := schemaModel(t, userSchema, `{"name":"ann","tags":["x"]}`)
+	if len(valid.jp.violations) != 0 || valid.status != "the input matches the schema" {
+		t.Errorf("valid document: %v, status %q", valid.jp.violations, valid.status)
+	}
+}
+
+func TestMarkViolations(t *testing.T) {
+	m := schemaModel(t, userSchema, `{"name":"a","user":{"age":-1},"tags":["x",2,3]}`)
+	nodes := make(map[string]*TreeNode)
+	walkTree(m.root, func(n *TreeNode) {
+		nodes[n.key] = n
+	})
+	for key, want := range map[string][2]int{
+		"name":     {0, 0},
+		"user":     {0, 1},
+		"user.age": {1, 0},
+		"tags":     {0, 2},
+		"tags[0]":  {0, 0},
+		"tags[1]":  {1, 0},
+		"tags[2]":  {1, 0},
+	} {
+		n := nodes[key]
+		if got := [2]int{len(n.violations), n.nestedViolations}; got != want {
+			t.Errorf("%s: violations, nested = %v, want %v", key, got, want)
+		}
+	}
+
+	// collapsed nodes holding errors are marked, expanded ones only on the failing values
+	if nodes["tags"].errorMarker(false) != "" || nodes["tags[1]"].errorMarker(false) != violationMarker {
+		t.Error("expanded tags marked or tags[1] not marked")
+	}
+	nodes["tags"].expanded = false
+	if nodes["tags"].errorMarker(false) != violationMarker {
+		t.Error("collapsed tags not marked")
+	}
+
+	// violations of paths missing from the tree are skipped
+	before := m.root.nestedViolations
+	markViolations(m.root, []violation{{path: jsonPath(nil).child("missing")}})
+	if m.root.nestedViolations != before || len(m.root.violations) != 0 {
+		t.Errorf("a missing path was marked on the root: %d nested, %d own", m.root.nestedViolations, len(m.root.violations))
+	}
+}
+
+func TestNextViolation(t *testing.T) {
+	m := schemaModel(t, userSchema, `{"user":{"age":-1},"tags":["x",2]}`)
+	next := tea.KeyPressMsg{Code: 'n', Mod: tea.ModAlt}
+	prev := tea.KeyPressMsg{Code: 'p', Mod: tea.ModAlt}
+	// the violations are visited in the order the validator reported them
+	for i, v := range m.jp.violations {
+		m = send(m, next)
+		want := fmt.Sprintf("error %d/3 at %s: ", i+1, v.path)
+		if len(v.path) == 0 {
+			want = fmt.Sprintf("error %d/3 in the document: ", i+1)
+		} else if got := m.rows[m.selectedIdx].key; got != v.path.String() {
+			t.Errorf("error %d selected %s, want %s", i+1, got, v.path)
+		}
+		if !strings.HasPrefix(m.status, want) {
+			t.Errorf("alt+n status %q, want the prefix %q", m.status, want)
+		}
+	}
+	if m = send(m, next); !strings.HasPrefix(m.status, "error 1/3") {
+		t.Errorf("alt+n does not wrap around: %q", m.status)
+	}
+	if m = send(m, prev); !strings.HasPrefix(m.status, "error 3/3") {
+		t.Errorf("alt+p does not wrap around: %q", m.status)
+	}
+
+	if m = send(newTestModel(t, `{}`), next); m.status != "no schema, start jex with --schema" {
+		t.Errorf("without a schema: %q", m.status)
+	}
+}
@@ -0,0 +1,685 @@
+package main
+
+import (
+	"container/heap"
+	"iter"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// valueSnippetWidth is the maximum width of a matching value shown next to a tree node
+const valueSnippetWidth = 40
+
+// SGR sequences wrapped around highlighted matches, reverse video composes with any coloring
+const (
+	matchStart = "\x1b[7m"
+	matchEnd   = "\x1b[27m"
+)
+
+// filterMatch describes a node kept in the tree while filtering
+type filterMatch struct {
+	node *TreeNode
+	// matched is false for ancestors that are only shown as context for a match
+	matched bool
+	// score is the best fuzzy score in the subtree, siblings are listed by it
+	score int
+	// children links the kept children, so that the rows are built without looking up every
+	// child of a large container
+	children []*filterMatch
+}
+
+// keepNode adds n to the filter along with its ancestors, which are expanded and hold the best
+// score below them
+func (m *Model) keepNode(n *TreeNode, score int) *filterMatch {
+	f := n.filter
+	if f == nil {
+		f = &filterMatch{node: n, score: score}
+		n.filter = f
+		if n != m.root {
+			n.parent.expanded = true
+			parent := m.keepNode(n.parent, score)
+			parent.children = append(parent.children, f)
+		}
+		return f
+	}
+	// ancestors score at least as high as their descendants, so the update stops early
+	if score > f.score && n != m.root {
+		f.score = score
+		m.keepNode(n.parent, score)
+	}
+	return f
+}
+
+// clearFilter detaches the active filter from the nodes it kept
+func (m *Model) clearFilter() {
+	var clear func(f *filterMatch)
+	clear = func(f *filterMatch) {
+		f.node.filter = nil
+		for _, child := range f.children {
+			clear(child)
+		}
+	}
+	if m.filterRoot != nil {
+		clear(m.filterRoot)
+		m.filterRoot = nil
+	}
+}
+
+// rank orders the kept children below f by their best score, ties keep document order
+func (f *filterMatch) rank() {
+	slices.SortStableFunc(f.children, func(a, b *filterMatch) int { return b.score - a.score })
+	for _, child := range f.children {
+		child.rank()
+	}
+}
+
+// filterCache is the result of the last filter, a query extended by typing only has to be
+// matched against the nodes that matched before: every match of "abc" also matches "ab", and
+// the ranges left unopened hold no match of "ab"
+type filterCache struct {
+	root    *TreeNode
+	mode    searchMode
+	query   string
+	matches nodeList
+}
+
+// filterMaxMatches is the number of matches kept in the tree, the best ones for key search and
+// the first ones for value search; listing more would only slow down every key press
+const filterMaxMatches = 5000
+
+// filterSlice is the time a filter scans before it lets the next message in, so that large trees
+// are filtered over several frames without delaying key presses
+const filterSlice = 8 * time.Millisecond
+
+// filterStepMsg continues the filter it was scheduled for
+type filterStepMsg struct {
+	id int
+}
+
+// filterJob matches a query against the nodes of a tree a time slice at a time
+type filterJob struct {
+	id    int
+	root  *TreeNode
+	mode  searchMode
+	query string
+	match func(n *TreeNode) (score int, ok bool)
+
+	// candidates are the previous matches when the query was extended, matched is the number of
+	// them matched so far; the walker visits the rest of the nodes
+	candidates nodeList
+	matched    int
+	walker     treeWalker
+
+	matches nodeList
+	top     topMatches
+
+	// opened lists the buckets search opened, hit holds the ones with a match once there are any
+	opened *openedBuckets
+	hit    map[*TreeNode]struct{}
+
+	// done is set once all nodes were matched
+	done bool
+}
+
+// openedBuckets are the buckets of a tree search opened to look at their elements, they are
+// closed again once no filter matches in them so that typing does not load every bucket
+type openedBuckets struct {
+	root    *TreeNode
+	buckets []openedBucket
+}
+
+// openedBucket is a bucket search opened and the documents it loaded its elements from
+type openedBucket struct {
+	node *TreeNode
+	docs []*JSONProcessor
+}
+
+// newFilterJob prepares matching query against the nodes of the tree, only the previous matches
+// are matched again when the query extends the last one
+func (m *Model) newFilterJob(mode searchMode, query string, match func(n *TreeNode) (int, bool)) *filterJob {
+	job := &filterJob{root: m.root, mode: mode, query: query, match: match, opened: m.opened}
+	c := m.filter
+	if c == nil || c.root != m.root || c.mode != mode || !strings.HasPrefix(query, c.query) {
+		job.walker.push(m.root)
+		return job
+	}
+	job.candidates = c.matches
+	return job
+}
+
+// next returns the next node to match, nil when all were matched
+func (job *filterJob) next() *TreeNode {
+	if job.matched < job.candidates.len() {
+		job.matched++
+		return job.candidates.at(job.matched - 1)
+	}
+	n := job.walker.next()
+	// an unopened range is opened when its data holds a match, the walker then visits its elements
+	if n != nil && n.lazy != nil && n.bucketMatches(job.match) {
+		job.opened.buckets = append(job.opened.buckets, openedBucket{node: n, docs: n.lazy})
+		n.loadBucket()
+	}
+	return n
+}
+
+// scan matches nodes until the deadline and reports whether all were matched
+// a zero deadline matches all of them at once
+func (job *filterJob) scan(deadline time.Time) bool {
+	for i := 1; ; i++ {
+		n := job.next()
+		if n == nil {
+			return true
+		}
+		if score, ok := job.match(n); ok {
+			job.matches.add(n)
+			job.top.add(scoredNode{node: n, score: score, order: job.matches.len()})
+			if len(job.opened.buckets) > 0 {
+				job.hit = markBuckets(job.hit, n)
+			}
+		}
+		// reading the clock costs more than matching a node, check it now and then and after a
+		// range, whose data was scanned
+		if (i%256 == 0 || n.isBucket()) && !deadline.IsZero() && time.Now().After(deadline) {
+			return false
+		}
+	}
+}
+
+// markBuckets adds the buckets holding n to hit
+func markBuckets(hit map[*TreeNode]struct{}, n *TreeNode) map[*TreeNode]struct{} {
+	if hit == nil {
+		hit = make(map[*TreeNode]struct{})
+	}
+	for ; n != nil; n = n.parent {
+		if n.isBucket() {
+			hit[n] = struct{}{}
+		}
+	}
+	return hit
+}
+
+// closeBuckets closes the buckets search opened that hold no match of job, or when the filter
+// was cleared, that do not hold the selected node
+func (m *Model) closeBuckets(job *filterJob) {
+	hit := job.hit
+	if job.match == nil && m.selectedIdx >= 0 && m.selectedIdx < len(m.rows) {
+		hit = markBuckets(hit, m.rows[m.selectedIdx])
+	}
+	open := m.opened.buckets[:0]
+	for _, b := range m.opened.buckets {
+		if _, ok := hit[b.node]; ok {
+			open = append(open, b)
+			continue
+		}
+		b.node.children, b.node.lazy, b.node.expanded = nil, b.docs, false
+	}
+	clear(m.opened.buckets[len(open):])
+	m.opened.buckets = open
+}
+
+// nodeListChunk is the number of nodes a chunk of a nodeList holds
+const nodeListChunk = 4096
+
+// nodeList is a list of nodes stored in chunks, it grows without copying the nodes added before,
+// which would stall a scan collecting millions of them
+type nodeList struct {
+	chunks [][]*TreeNode
+}
+
+// add appends n to the list
+func (l *nodeList) add(n *TreeNode) {
+	if len(l.chunks) == 0 || len(l.chunks[len(l.chunks)-1]) == nodeListChunk {
+		l.chunks = append(l.chunks, make([]*TreeNode, 0, nodeListChunk))
+	}
+	last := &l.chunks[len(l.chunks)-1]
+	*last = append(*last, n)
+}
+
+// len returns the number of nodes in the list
+func (l nodeList) len() int {
+	if len(l.chunks) == 0 {
+		return 0
+	}
+	return (len(l.chunks)-1)*nodeListChunk + len(l.chunks[len(l.chunks)-1])
+}
+
+// at returns the node at index i
+func (l nodeList) at(i int) *TreeNode {
+	return l.chunks[i/nodeListChunk][i%nodeListChunk]
+}
+
+// all returns the nodes of the list in order
+func (l nodeList) all() iter.Seq[*TreeNode] {
+	return func(yield func(*TreeNode) bool) {
+		for _, chunk := range l.chunks {
+			for _, n := range chunk {
+				if !yield(n) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// treeWalker visits the nodes below the roots pushed to it in document order, one at a time
+type treeWalker struct {
+	stack []treeCursor
+}
+
+// treeCursor is a node on the walker's stack and the index of its next child to visit
+type treeCursor struct {
+	node *TreeNode
+	next int
+}
+
+// push makes the walker visit the nodes below n next
+func (w *treeWalker) push(n *TreeNode) {
+	w.stack = append(w.stack, treeCursor{node: n})
+}
+
+// next returns the next node, nil when the walk is done
+func (w *treeWalker) next() *TreeNode {
+	for len(w.stack) > 0 {
+		top := &w.stack[len(w.stack)-1]
+		if top.next == len(top.node.children) {
+			w.stack = w.stack[:len(w.stack)-1]
+			continue
+		}
+		child := top.node.children[top.next]
+		top.next++
+		w.push(child)
+		return child
+	}
+	return nil
+}
+
+// scoredNode is a match, order is its place among the matches
+type scoredNode struct {
+	node  *TreeNode
+	score int
+	order int
+}
+
+// worse reports whether a ranks below b, of equal scores the later match
+func (a scoredNode) worse(b scoredNode) bool {
+	return a.score < b.score || a.score == b.score && a.order > b.order
+}
+
+// topMatches is a heap of the best filterMaxMatches matches, the worst one on top
+type topMatches []scoredNode
+
+func (t topMatches) Len() int           { return len(t) }
+func (t topMatches) Less(i, j int) bool { return t[i].worse(t[j]) }
+func (t topMatches) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t *topMatches) Push(x any)        { *t = append(*t, x.(scoredNode)) }
+func (t *topMatches) Pop() any {
+	old := *t
+	x := old[len(old)-1]
+	*t = old[:len(old)-1]
+	return x
+}
+
+// add keeps s if it is among the best matches
+func (t *topMatches) add(s scoredNode) {
+	switch {
+	case len(*t) < filterMaxMatches:
+		heap.Push(t, s)
+	case (*t)[0].worse(s):
+		(*t)[0] = s
+		heap.Fix(t, 0)
+	}
+}
+
+// namePositions maps matched key positions onto the node name shown in the tree
+func namePositions(node *TreeNode, positions []int) []int {
+	// the name is the last segment of the key, quoted keys keep it between the quotes
+	at := strings.LastIndex(node.key, node.name)
+	if len(positions) == 0 || at < 0 {
+		return nil
+	}
+	offset := utf8.RuneCountInString(node.key[:at])
+	var mapped []int
+	for _, pos := range positions {
+		if pos >= offset {
+			mapped = append(mapped, pos-offset)
+		}
+	}
+	return mapped
+}
+
+// foldRunes lower-cases s rune by rune so that rune offsets are kept
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// indexRunes returns the index of the first occurrence of needle in hay, or -1
+func indexRunes(hay, needle []rune) int {
+	if len(needle) == 0 {
+		return -1
+	}
+	for i := 0; i+len(needle) <= len(hay); i++ {
+		match := true
+		for j, r := range needle {
+			if hay[i+j] != r {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchValue returns the first leaf value containing query, ignoring case, and the rune offset of the match
+// the offset is -1 when no value matches
+func (n *TreeNode) matchValue(query string) (string, int) {
+	needle := foldRunes(query)
+	for _, value := range n.values {
+		if idx := indexRunes(foldRunes(value), needle); idx >= 0 {
+			return value, idx
+		}
+	}
+	return "", -1
+}
+
+// hasValue reports whether a leaf value contains needle, which must be folded with foldRunes
+// it does not allocate, so that filtering many nodes stays cheap
+func (n *TreeNode) hasValue(needle []rune) bool {
+	for _, value := range n.values {
+		if containsFold(value, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether s contains needle, ignoring case
+func containsFold(s string, needle []rune) bool {
+	if len(needle) == 0 {
+		return false
+	}
+	for i := range s {
+		j := 0
+		for _, r := range s[i:] {
+			if unicode.ToLower(r) != needle[j] {
+				break
+			}
+			if j++; j == len(needle) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// valueSnippet returns the part of a matching value shown next to a tree node
+func valueSnippet(value string, idx int) string {
+	runes := []rune(strings.ReplaceAll(value, "\n", " "))
+	start := max(0, idx-valueSnippetWidth/4)
+	end := min(len(runes), start+valueSnippetWidth)
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// highlightMatches marks every occurrence of query in text, ignoring case
+// text may already contain ANSI escape sequences, which are skipped while matching
+func highlightMatches(text, query string) string {
+	needle := foldRunes(query)
+	if len(needle) == 0 {
+		return text
+	}
+
+	// collect the visible runes and their byte offsets
+	var visible []rune
+	var offsets []int
+	for i := 0; i < len(text); {
+		if text[i] == '\x1b' && i+1 < len(text) && text[i+1] == '[' {
+			j := i + 2
+			for j < len(text) && (text[j] < 0x40 || text[j] > 0x7e) {
+				j++
+			}
+			i = j + 1
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		visible = append(visible, unicode.ToLower(r))
+		offsets = append(offsets, i)
+		i += size
+	}
+
+	var b strings.Builder
+	last := 0
+	for pos := 0; pos < len(visible); {
+		idx := indexRunes(visible[pos:], needle)
+		if idx < 0 {
+			break
+		}
+		start := pos + idx
+		end := start + len(needle)
+		b.WriteString(text[last:offsets[start]])
+		b.WriteString(matchStart)
+		endByte := len(text)
+		if end < len(offsets) {
+			endByte = offsets[end]
+		}
+		// keep escape sequences inside the match so that coloring is preserved
+		b.WriteString(text[offsets[start]:endByte])
+		b.WriteString(matchEnd)
+		last = endByte
+		pos = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Fuzzy match scores, modelled after fzf
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+	bonusBoundary     = 8
+	bonusCamelCase    = 7
+	bonusConsecutive  = 4
+	bonusLastSegment  = 2
+	bonusFirstCharMul = 2
+)
+
+// isKeySeparator reports whether r separates the segments of a key path
+func isKeySeparator(r rune) bool {
+	switch r {
+	case '.', '[', ']', '"', '_', '-', '/', ' ', ':', '#':
+		return true
+	}
+	return false
+}
+
+// fuzzyMatch matches query against key as a subsequence, ignoring case
+// it returns the best alignment score and the rune positions of the matched characters;
+// matches at segment starts, in consecutive runs and in the last path segment score higher
+func fuzzyMatch(key, query string) (score int, positions []int, ok bool) {
+	return newFuzzyMatcher(query).match(key, true)
+}
+
+// fuzzyMatcher matches one query against many keys, reusing its buffers between keys
+type fuzzyMatcher struct {
+	needle []rune
+
+	// scratch space for the key being scored, scores and from are needle × key matrices
+	runes  []rune
+	hay    []rune
+	bonus  []int
+	scores []int
+	from   []int
+}
+
+// newFuzzyMatcher prepares query for matching
+func newFuzzyMatcher(query string) *fuzzyMatcher {
+	return &fuzzyMatcher{needle: foldRunes(query)}
+}
+
+// isSubsequence reports whether the needle occurs in key as a subsequence, ignoring case,
+// without allocating; most keys fail here and are never scored
+func (f *fuzzyMatcher) isSubsequence(key string) bool {
+	i := 0
+	for _, r := range key {
+		if i == len(f.needle) {
+			break
+		}
+		if unicode.ToLower(r) == f.needle[i] {
+			i++
+		}
+	}
+	return i == len(f.needle)
+}
+
+// match scores key, positions are only returned when withPositions is set
+func (f *fuzzyMatcher) match(key string, withPositions bool) (score int, positions []int, ok bool) {
+	needle := f.needle
+	if len(needle) == 0 {
+		return 0, nil, true
+	}
+	if !f.isSubsequence(key) {
+		return 0, nil, false
+	}
+	f.runes = append(f.runes[:0], []rune(key)...)
+	f.hay = f.hay[:0]
+	for _, r := range f.runes {
+		f.hay = append(f.hay, unicode.ToLower(r))
+	}
+	runes, hay := f.runes, f.hay
+
+	n, m := len(hay), len(needle)
+	lastSegment := 0
+	for j, r := range runes {
+		if r == '.' || r == '[' {
+			lastSegment = j
+		}
+	}
+	f.bonus = resize(f.bonus, n)
+	bonus := f.bonus
+	for j, r := range runes {
+		bonus[j] = 0
+		switch {
+		case j == 0 || isKeySeparator(runes[j-1]):
+			bonus[j] = bonusBoundary
+		case unicode.IsLower(runes[j-1]) && unicode.IsUpper(r):
+			bonus[j] = bonusCamelCase
+		}
+		if j >= lastSegment {
+			bonus[j] += bonusLastSegment
+		}
+	}
+
+	// scores[i*n+j] is the best score with needle[i] matched at hay[j],
+	// from[i*n+j] is the position needle[i-1] was matched at on that alignment
+	const none = -1 << 30
+	f.scores = resize(f.scores, m*n)
+	f.from = resize(f.from, m*n)
+	scores, from := f.scores, f.from
+	for i := range needle {
+		row, prevRow := scores[i*n:(i+1)*n], scores[max(i-1, 0)*n:]
+		gap, gapFrom := none, -1
+		for j := 0; j < n; j++ {
+			if i > 0 && j >= 2 {
+				// extend the running gap or open a new one after needle[i-1] at j-2
+				gap += scoreGapExtension
+				if open := prevRow[j-2] + scoreGapStart; open > gap {
+					gap, gapFrom = open, j-2
+				}
+			}
+			row[j] = none
+			if hay[j] != needle[i] {
+				continue
+			}
+			if i == 0 {
+				row[j] = scoreMatch + bonus[j]*bonusFirstCharMul
+				from[j] = -1
+				continue
+			}
+			prev, prevFrom := gap, gapFrom
+			if j >= 1 && prevRow[j-1] > none {
+				if consecutive := prevRow[j-1] + bonusConsecutive; consecutive >= prev {
+					prev, prevFrom = consecutive, j-1
+				}
+			}
+			if prevFrom < 0 || prev <= none/2 {
+				continue
+			}
+			row[j] = prev + scoreMatch + bonus[j]
+			from[i*n+j] = prevFrom
+		}
+	}
+
+	best, end := none, -1
+	for j, s := range scores[(m-1)*n : m*n] {
+		if s > best {
+			best, end = s, j
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	if !withPositions {
+		return best, nil, true
+	}
+
+	positions = make([]int, m)
+	for i, j := m-1, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i*n+j]
+	}
+	return best, positions, true
+}
+
+// resize returns a slice of length n, reusing the array of s when it is large enough
+func resize(s []int, n int) []int {
+	if cap(s) < n {
+		return make([]int, n)
+	}
+	return s[:n]
+}
+
+// highlightPositions marks the runes of text at the given positions
+func highlightPositions(text string, positions []int) string {
+	if len(positions) == 0 {
+		return text
+	}
+	marked := make(map[int]struct{}, len(positions))
+	for _, pos := range positions {
+		marked[pos] = struct{}{}
+	}
+
+	var b strings.Builder
+	inMatch := false
+	idx := 0
+	for _, r := range text {
+		_, isMarked := marked[idx]
+		if isMarked && !inMatch {
+			b.WriteString(matchStart)
+		} else if !isMarked && inMatch {
+			b.WriteString(matchEnd)
+		}
+		inMatch = isMarked
+		b.WriteRune(r)
+		idx++
+	}
+	if inMatch {
+		b.WriteString(matchEnd)
+	}
+	return b.String()
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		key, query string
+		ok         bool
+		positions  []int
+	}{
+		{"name", "", true, nil},
+		{"name", "nm", true, []int{0, 2}},
+		{"Name", "NAME", true, []int{0, 1, 2, 3}},
+		{"company.departments[0].name", "dname", true, []int{8, 23, 24, 25, 26}},
+		{"name", "mn", false, nil},
+		{"name", "names", false, nil},
+		{"日本語.キー", "キー", true, []int{4, 5}},
+	}
+	for _, tt := range tests {
+		_, positions, ok := fuzzyMatch(tt.key, tt.query)
+		if ok != tt.ok || !slices.Equal(positions, tt.positions) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %v, want %v, %v", tt.key, tt.query, positions, ok, tt.positions, tt.ok)
+		}
+	}
+}
+
+func TestFuzzyMatchRanking(t *testing.T) {
+	// the first key of every pair scores higher than the second
+	tests := []struct {
+		query, better, worse string
+	}{
+		{"name", "user.name", "user.nickname"},       // consecutive run over a gapped match
+		{"dn", "data.name", "hidden"},                // segment starts over inner characters
+		{"id", "user.id", "identity.user"},           // last segment over an earlier one
+		{"n", "userName", "sun"},                     // camel case boundary over an inner character
+		{"ab", "a.b", "a.xxxxxxxxb"},                 // shorter gap over a longer one
+		{"mem", "items[].meta.member", "items[].me"}, // a full match over none
+	}
+	for _, tt := range tests {
+		better, _, ok := fuzzyMatch(tt.better, tt.query)
+		if !ok {
+			t.Errorf("fuzzyMatch(%q, %q) did not match", tt.better, tt.query)
+			continue
+		}
+		worse, _, ok := fuzzyMatch(tt.worse, tt.query)
+		if ok && worse >= better {
+			t.Errorf("query %q: %q scores %d, not above %q with %d", tt.query, tt.better, better, tt.worse, worse)
+		}
+	}
+}
+
+func TestKeySearchRanking(t *testing.T) {
+	m := typeText(newTestModel(t, `{"user":{"nickname":"n","age":3,"name":"a"}}`), "name")
+	if got, want := rowKeys(m), []string{"user", "user.name", "user.nickname"}; !slices.Equal(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+	if got := m.rows[m.selectedIdx].key; got != "user.name" {
+		t.Errorf("selected %s, want the best match user.name", got)
+	}
+	if row := m.formatTreeItem(m.rows[1], false); !strings.Contains(row, matchStart+"name"+matchEnd) {
+		t.Errorf("matched characters not highlighted in %q", row)
+	}
+}
+
+func TestHighlightPositions(t *testing.T) {
+	const on, off = matchStart, matchEnd
+	tests := []struct {
+		text      string
+		positions []int
+		want      string
+	}{
+		{"name", nil, "name"},
+		{"name", []int{0, 2}, on + "n" + off + "a" + on + "m" + off + "e"},
+		{"name", []int{2, 3}, "na" + on + "me" + off},
+		{"日本語", []int{1}, "日" + on + "本" + off + "語"},
+	}
+	for _, tt := range tests {
+		if got := highlightPositions(tt.text, tt.positions); got != tt.want {
+			t.Errorf("highlightPositions(%q, %v) = %q, want %q", tt.text, tt.positions, got, tt.want)
+		}
+	}
+}
+
+const regionDoc = `{"name":"web","zones":[{"region":"us-east-1","port":443},{"region":"eu-west-1","port":80}],"dns":{"primary":"US-EAST-1.example.com"},"enabled":true}`
+
+func TestValueSearch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// ancestors are listed as context for the matching leaves
+		{"us-e", []string{"zones", "zones[0]", "zones[0].region", "dns", "dns.primary"}},
+		{"443", []string{"zones", "zones[0]", "zones[0].port"}},
+		{"TRUE", []string{"enabled"}},
+		{"nothing", nil},
+	}
+	for _, tt := range tests {
+		m := newTestModel(t, regionDoc)
+		m = typeText(send(m, ctrlKey('s')), tt.query)
+		if m.searchMode != searchValues || m.valueQuery != tt.query {
+			t.Fatalf("value search mode %d with query %q", m.searchMode, m.valueQuery)
+		}
+		if got := rowKeys(m); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: rows = %v, want %v", tt.query, got, tt.want)
+		}
+		if len(tt.want) > 0 && m.matchSnippet(m.rows[m.selectedIdx]) == "" {
+			t.Errorf("%s: selected %s, which does not match", tt.query, m.rows[m.selectedIdx].key)
+		}
+	}
+
+	// the key search query is kept while values are searched, and filters again afterwards
+	m := typeText(newTestModel(t, regionDoc), "port")
+	m = typeText(send(m, ctrlKey('s')), "eu")
+	if got := rowKeys(m); !slices.Equal(got, []string{"zones", "zones[1]", "zones[1].region"}) {
+		t.Errorf("value rows = %v", got)
+	}
+	m = send(m, ctrlKey('s'), ctrlKey('s'))
+	if m.searchQuery != "port" || !slices.Contains(rowKeys(m), "zones[1].port") || slices.Contains(rowKeys(m), "zones[1].region") {
+		t.Errorf("back in key search with %q: rows = %v", m.searchQuery, rowKeys(m))
+	}
+}
+
+func TestMatchSnippet(t *testing.T) {
+	m := typeText(send(newTestModel(t, regionDoc), ctrlKey('s')), "east")
+	snippets := make(map[string]string)
+	for _, row := range m.rows {
+		snippets[row.key] = m.matchSnippet(row)
+	}
+	want := map[string]string{"zones": "", "zones[0]": "", "zones[0].region": "us-east-1", "dns": "", "dns.primary": "US-EAST-1.example.com"}
+	for key, snippet := range want {
+		if snippets[key] != snippet {
+			t.Errorf("snippet of %s = %q, want %q", key, snippets[key], snippet)
+		}
+	}
+	if !strings.Contains(m.formatTreeItem(m.rows[2], false), "us-"+matchStart+"east"+matchEnd+"-1") {
+		t.Errorf("the tree row does not highlight the match: %q", m.formatTreeItem(m.rows[2], false))
+	}
+}
+
+func TestValueSnippet(t *testing.T) {
+	long := strings.Repeat("a", 30) + "needle" + strings.Repeat("b", 30)
+	tests := []struct {
+		value string
+		idx   int
+		want  string
+	}{
+		{"short", 0, "short"},
+		{"line\nbreak", 5, "line break"},
+		{long, 30, "…" + long[20:60] + "…"},
+		{long, 2, long[:40] + "…"},
+	}
+	for _, tt := range tests {
+		if got := valueSnippet(tt.value, tt.idx); got != tt.want {
+			t.Errorf("valueSnippet(%q, %d) = %q, want %q", tt.value, tt.idx, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	const on, off = matchStart, matchEnd
+	tests := []struct {
+		text, query, want string
+	}{
+		{"us-east-1", "EAST", "us-" + on + "east" + off + "-1"},
+		{"abab", "ab", on + "ab" + off + on + "ab" + off},
+		{"Ärger", "är", on + "Är" + off + "ger"},
+		{"nothing", "x", "nothing"},
+		{"text", "", "text"},
+		// escape sequences are skipped while matching and kept inside the match
+		{"\x1b[32mus-\x1b[0meast", "s-e", "\x1b[32mu" + on + "s-\x1b[0me" + off + "ast"},
+	}
+	for _, tt := range tests {
+		if got := highlightMatches(tt.text, tt.query); got != tt.want {
+			t.Errorf("highlightMatches(%q, %q) = %q, want %q", tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyMatcherReuse(t *testing.T) {
+	// the buffers of a matcher are reused, scores must not depend on the keys matched before
+	keys := []string{"a.very.long.key.with.many.segments", "name", "user.name", "n", "names.list"}
+	matcher := newFuzzyMatcher("na")
+	for _, key := range keys {
+		score, positions, ok := matcher.match(key, true)
+		wantScore, wantPositions, wantOK := newFuzzyMatcher("na").match(key, true)
+		if score != wantScore || ok != wantOK || !slices.Equal(positions, wantPositions) {
+			t.Errorf("match(%q) = %d, %v, %v, want %d, %v, %v", key, score, positions, ok, wantScore, wantPositions, wantOK)
+		}
+	}
+}
+
+func TestContainsFold(t *testing.T) {
+	tests := []struct {
+		s, needle string
+		want      bool
+	}{
+		{"us-east-1", "us-e", true},
+		{"US-EAST-1", "us-e", true},
+		{"us-west-1", "us-e", false},
+		{"uus-e", "us-e", true},
+		{"Ärger", "är", true},
+		{"value", "", false},
+	}
+	for _, tt := range tests {
+		if got := containsFold(tt.s, foldRunes(tt.needle)); got != tt.want {
+			t.Errorf("containsFold(%q, %q) = %v, want %v", tt.s, tt.needle, got, tt.want)
+		}
+	}
+}
+
+// filterCandidates returns the nodes a filter for query is matched against
+func filterCandidates(m Model, query string) []*TreeNode {
+	job := m.newFilterJob(searchKeys, query, nil)
+	var nodes []*TreeNode
+	for n := job.next(); n != nil; n = job.next() {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+func TestFilterNarrowsPreviousMatches(t *testing.T) {
+	m := newTestModel(t, `{"name":"a","nickname":"b","meta":{"member":1,"names":[1,2]},"other":true}`)
+
+	var previous []*TreeNode
+	for _, query := range []string{"n", "na", "nam", "name"} {
+		m.searchQuery = query
+		if previous != nil {
+			nodes := filterCandidates(m, query)
+			if !slices.Equal(nodes, previous) {
+				t.Fatalf("query %q is matched against %d nodes, want the %d previous matches", query, len(nodes), len(previous))
+			}
+		}
+		m.updateFilteredKeys()
+		for n := range m.filter.matches.all() {
+			if previous != nil && !slices.Contains(previous, n) {
+				t.Errorf("query %q matches %s, which %q did not match", query, n.key, m.filter.query)
+			}
+		}
+		previous = slices.Collect(m.filter.matches.all())
+	}
+	if len(previous) == 0 {
+		t.Fatal("name matches nothing")
+	}
+
+	// a query that does not extend the previous one scans the whole tree again
+	m.searchQuery = "other"
+	nodes := filterCandidates(m, m.searchQuery)
+	if len(nodes) <= len(previous) {
+		t.Errorf("query other is matched against %d nodes, want the whole tree", len(nodes))
+	}
+	m.updateFilteredKeys()
+	if m.filter.matches.len() != 1 || m.filter.matches.at(0).key != "other" {
+		t.Errorf("query other matches %d nodes, want only other", m.filter.matches.len())
+	}
+}
+
+func TestFilterKeepsAncestors(t *testing.T) {
+	m := newTestModel(t, `{"xtarget":3,"a":{"b":{"target":1},"c":2}}`)
+	m.searchQuery = "target"
+	m.updateFilteredKeys()
+
+	keys := rowKeys(m)
+	// the better match is listed first, with its ancestors as context
+	want := []string{"a", "a.b", "a.b.target", "xtarget"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("rows = %v, want %v", keys, want)
+	}
+	if m.rows[m.selectedIdx].filter == nil || !m.rows[m.selectedIdx].filter.matched {
+		t.Errorf("selected row %s is not a match", m.rows[m.selectedIdx].key)
+	}
+
+	m.searchQuery = ""
+	m.updateFilteredKeys()
+	if m.filterRoot != nil || m.root.children[0].filter != nil {
+		t.Error("clearing the query leaves the filter attached to the tree")
+	}
+}
+
+func TestFilterInSlices(t *testing.T) {
+	data := string(wideDocument(100))
+	want := newTestModel(t, data)
+	want.searchQuery = "name"
+	want.updateFilteredKeys()
+
+	m := newTestModel(t, data)
+	m.searchQuery = "name"
+	m.filterJob = m.startFilter()
+	// a deadline that passed stops the scan after every few hundred nodes
+	steps := 1
+	for !m.filterJob.scan(time.Now()) {
+		steps++
+	}
+	if steps < 4 {
+		t.Errorf("scanned %d nodes in %d steps", countNodes(m.root), steps)
+	}
+	m.applyFilter()
+	if got := rowKeys(m); !slices.Equal(got, rowKeys(want)) {
+		t.Errorf("rows filtered in steps = %v, want %v", got, rowKeys(want))
+	}
+
+	// a step of a filter replaced by a later key press is dropped
+	m = newTestModel(t, data)
+	m.searchQuery = "name"
+	m.filterJob = m.startFilter()
+	stale := m.filterJob.id
+	m.searchQuery = "email"
+	m.filterJob = m.startFilter()
+	job := m.filterJob
+	next, _ := m.Update(filterStepMsg{id: stale})
+	if m = next.(Model); m.filterJob != job || m.filterRoot != nil {
+		t.Fatal("a stale step ran")
+	}
+	next, _ = m.Update(filterStepMsg{id: job.id})
+	if m = next.(Model); m.filterJob != nil || m.filter.query != "email" {
+		t.Errorf("the current step did not apply the filter, query %q", m.filter.query)
+	}
+}
+
+func TestFilterMaxMatches(t *testing.T) {
+	var b strings.Builder
+	// k in the middle of a word scores below k at the start of one
+	b.WriteString("{")
+	for i := 1; i <= filterMaxMatches+10; i++ {
+		fmt.Fprintf(&b, `"xk%d":%d,`, i, i)
+	}
+	b.WriteString(`"key":0}`)
+	m := newTestModel(t, b.String())
+	m.searchQuery = "k"
+	m.updateFilteredKeys()
+
+	matched := 0
+	for _, row := range m.rows {
+		if row.filter.matched {
+			matched++
+		}
+	}
+	if matched != filterMaxMatches || m.filter.matches.len() != filterMaxMatches+11 {
+		t.Errorf("%d of %d matches kept, want %d", matched, m.filter.matches.len(), filterMaxMatches)
+	}
+	if want := fmt.Sprintf("showing %d of %d matches", filterMaxMatches, filterMaxMatches+11); m.status != want {
+		t.Errorf("status %q, want %q", m.status, want)
+	}
+	// the best match is kept and listed first although it comes last
+	if m.rows[0].key != "key" || m.rows[len(m.rows)-1].key != fmt.Sprintf("xk%d", filterMaxMatches-1) {
+		t.Errorf("rows go from %s to %s, want from key to xk%d", m.rows[0].key, m.rows[len(m.rows)-1].key, filterMaxMatches-1)
+	}
+}
+
+func TestTypingDefersExtract(t *testing.T) {
+	m := newTestModel(t, `{"name":"first","other":"second"}`)
+	m = send(m, tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	// every key press schedules a render instead of rendering the extractor
+	var ticks []tea.Cmd
+	for _, r := range "ot" {
+		next, cmd := m.Update(tea.KeyPressMsg{Code: r, Text: string(r)})
+		m = next.(Model)
+		if !m.extractDue || cmd == nil {
+			t.Fatalf("typing %q renders the extractor instead of scheduling it", r)
+		}
+		ticks = append(ticks, cmd)
+	}
+	if strings.Contains(m.extractRaw, "second") {
+		t.Error("the extractor shows the new selection before typing paused")
+	}
+
+	// the tick of the first key press is outdated and dropped without scheduling another one
+	next, cmd := m.Update(ticks[0]())
+	m = next.(Model)
+	if !m.extractDue || cmd != nil {
+		t.Errorf("the outdated tick rendered or rescheduled: due %v, command %v", m.extractDue, cmd != nil)
+	}
+	next, cmd = m.Update(ticks[1]())
+	m = next.(Model)
+	if m.extractDue || cmd != nil || !strings.Contains(m.extractRaw, "second") {
+		t.Errorf("the last tick did not render the selection: due %v, command %v, extractor %q", m.extractDue, cmd != nil, m.extractRaw)
+	}
+
+	// moving the selection renders at once
+	next, cmd = m.Update(tea.KeyPressMsg{Code: tea.KeyUp})
+	if m = next.(Model); m.extractDue || cmd != nil {
+		t.Errorf("moving the selection left a render pending: due %v, command %v", m.extractDue, cmd != nil)
+	}
+}
+
+// wideDocument generates an object of n members of about 175 bytes and 11 tree nodes each,
+// objects are not grouped into ranges so every node is built
+func wideDocument(n int) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `"user-%d":{"id":%d,"name":"user-%d","email":"user%d@example.com","meta":{"region":"us-east-%d","tags":["alpha","beta"],"score":%d.5},"created":"2024-01-%02dT10:00:00Z"}`, i, i, i, i, i%4, i%100, i%28+1)
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
+
+// countNodes returns the number of nodes below n
+func countNodes(n *TreeNode) int {
+	count := len(n.children)
+	for _, child := range n.children {
+		count += countNodes(child)
+	}
+	return count
+}
+
+// frameBudget is the time a message may take, one frame at 60 Hz
+const frameBudget = 16 * time.Millisecond
+
+// pressKey handles a key press and the messages following it until the filter is applied and the
+// extractor rendered, it returns the longest time one message took
+func pressKey(m Model, r rune) (Model, time.Duration) {
+	var worst time.Duration
+	handle := func(msg tea.Msg) {
+		start := time.Now()
+		next, _ := m.Update(msg)
+		m = next.(Model)
+		worst = max(worst, time.Since(start))
+	}
+	handle(tea.KeyPressMsg{Code: r, Text: string(r)})
+	for m.filterJob != nil {
+		handle(filterStepMsg{id: m.filterJob.id})
+	}
+	if m.extractDue {
+		// the tick would fire here once typing pauses
+		handle(renderExtractMsg{id: m.extractID})
+	}
+	return m, worst
+}
+
+// BenchmarkFilterKeystroke measures typing a query into a document of millions of tree nodes,
+// an operation is a key press until its result is shown with the extractor rendered
+// no message handled on the way, the key press included, may take more than a frame
+func BenchmarkFilterKeystroke(b *testing.B) {
+	jp := &JSONProcessor{jsonData: wideDocument(200000)}
+	jp.extractKeys()
+	m := NewBubbleteaModel(jp, "bench.json")
+	m = send(m, tea.WindowSizeMsg{Width: 200, Height: 50})
+	b.Logf("%d MB, %d tree nodes", len(jp.jsonData)>>20, countNodes(m.root))
+
+	for _, bench := range []struct {
+		name  string
+		mode  searchMode
+		query string
+	}{
+		{"keys", searchKeys, "mem"},
+		{"values", searchValues, "us-e"},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			m.searchMode = bench.mode
+			var worst time.Duration
+			for i := 0; i < b.N; i++ {
+				at := i % len(bench.query)
+				if at == 0 {
+					b.StopTimer()
+					*m.inputText(), m.searchCursor = "", 0
+					m.updateFilteredKeys()
+					b.StartTimer()
+				}
+				var took time.Duration
+				m, took = pressKey(m, rune(bench.query[at]))
+				worst = max(worst, took)
+			}
+			b.ReportMetric(float64(worst)/float64(time.Millisecond), "max-ms/msg")
+			if worst > frameBudget {
+				b.Errorf("a message took %v, over the %v frame budget", worst, frameBudget)
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tidwall/gjson"
+)
+
+// sourceContext is the number of lines the source view shows above the selected node
+const sourceContext = 200
+
+// sourceLineWidth is the number of bytes the source view shows of a line, minified documents
+// are shown around the selected node
+const sourceLineWidth = 4096
+
+var (
+	sourceGutterStyle = lipgloss.NewStyle().Foreground(tnComment)
+	sourceSpanStyle   = lipgloss.NewStyle().Background(tnSelection).Foreground(tnFg)
+)
+
+// sourceSpan is the byte range of the value at a node in the data of its document
+type sourceSpan struct {
+	start, end int // end is 0 when the range is unknown
+}
+
+// sourceLocation is the place of a node in the input as it was read
+type sourceLocation struct {
+	start, end int // byte range in the input, end is the end of the line when the size is unknown
+	line, col  int // 1-based position of start, col counts characters
+}
+
+func (l sourceLocation) String() string {
+	return fmt.Sprintf("line %d, column %d, byte %d", l.line, l.col, l.start)
+}
+
+// sourceMap maps offsets in converted JSON back to the input it was converted from
+// it holds the start of every token, both offsets increase with the index; the end of a token
+// is recorded as well where its converted form differs in length, -1 when it is not known
+type sourceMap struct {
+	out    []int
+	src    []int
+	outEnd []int
+	srcEnd []int
+}
+
+// add records that the token at out in the converted data starts at src in the input
+func (s *sourceMap) add(out, src int) {
+	s.out = append(s.out, out)
+	s.src = append(s.src, src)
+	s.outEnd = append(s.outEnd, -1)
+	s.srcEnd = append(s.srcEnd, -1)
+}
+
+// end records that the last token added ends at out in the converted data and at src in the
+// input, unless its end is known already
+func (s *sourceMap) end(out, src int) {
+	if i := len(s.out) - 1; i >= 0 && s.outEnd[i] < 0 {
+		s.outEnd[i], s.srcEnd[i] = out, src
+	}
+}
+
+// sourceOffset returns the input offset of an offset in the converted data
+func (s *sourceMap) sourceOffset(out int) int {
+	i := sort.SearchInts(s.out, out+1) - 1
+	if i < 0 {
+		return out
+	}
+	src := s.src[i] + out - s.out[i]
+	if i+1 < len(s.src) {
+		// tokens may shrink when they are converted, e.g. 0x10 to 16
+		src = min(src, s.src[i+1])
+	}
+	return src
+}
+
+// sourceEnd returns the input offset of the end of a range ending at out in the converted data
+func (s *sourceMap) sourceEnd(out int) int {
+	if i := sort.SearchInts(s.out, out) - 1; i >= 0 && s.outEnd[i] == out {
+		return s.srcEnd[i]
+	}
+	return s.sourceOffset(out-1) + 1
+}
+
+// outputOffset returns the offset in the converted data of an input offset
+func (s *sourceMap) outputOffset(src int) int {
+	i := sort.SearchInts(s.src, src+1) - 1
+	if i < 0 {
+		return src
+	}
+	out := s.out[i] + src - s.src[i]
+	if i+1 < len(s.out) {
+		out = min(out, s.out[i+1])
+	}
+	return out
+}
+
+// inputText returns the input as it was read, before any conversion to JSON
+func (jp *JSONProcessor) inputText() []byte {
+	if jp.source != nil {
+		return jp.source
+	}
+	return jp.jsonData
+}
+
+// toSource maps an offset in jsonData to the input
+func (jp *JSONProcessor) toSource(offset int) int {
+	if jp.sourceMap == nil {
+		return offset
+	}
+	return jp.sourceMap.sourceOffset(offset)
+}
+
+// toSourceEnd maps the end of a range in jsonData to the input
+func (jp *JSONProcessor) toSourceEnd(offset int) int {
+	if jp.sourceMap == nil {
+		return offset
+	}
+	return jp.sourceMap.sourceEnd(offset)
+}
+
+// fromSource maps an offset in the input to jsonData
+func (jp *JSONProcessor) fromSource(offset int) int {
+	if jp.sourceMap == nil {
+		return offset
+	}
+	return jp.sourceMap.outputOffset(offset)
+}
+
+// lineStarts returns the offset of every line of the input, indexed once on first use
+func (jp *JSONProcessor) lineStarts() []int {
+	if jp.lines == nil {
+		data := jp.inputText()
+		jp.lines = []int{0}
+		for i := 0; ; {
+			next := bytes.IndexByte(data[i:], '\n')
+			if next < 0 {
+				break
+			}
+			i += next + 1
+			jp.lines = append(jp.lines, i)
+		}
+	}
+	return jp.lines
+}
+
+// position returns the 1-based line and column of an offset in the input, the column in characters
+func (jp *JSONProcessor) position(offset int) (line, col int) {
+	data := jp.inputText()
+	offset = min(max(offset, 0), len(data))
+	lines := jp.lineStarts()
+	line = sort.SearchInts(lines, offset+1)
+	return line, utf8.RuneCount(data[lines[line-1]:offset]) + 1
+}
+
+// lineOffset returns the offset in the input of a 1-based line and column
+// col 0 stands for the first character of the line that is not a space
+func (jp *JSONProcessor) lineOffset(line, col int) (int, error) {
+	data := jp.inputText()
+	lines := jp.lineStarts()
+	if line < 1 || line > len(lines) {
+		return 0, fmt.Errorf("the input has %d lines", len(lines))
+	}
+	start, end := jp.lineRange(line)
+	text := data[start:end]
+	if col == 0 {
+		return start + len(text) - len(bytes.TrimLeft(text, " \t\r")), nil
+	}
+	offset := start
+	for i := 1; i < col; i++ {
+		if offset >= end {
+			return 0, fmt.Errorf("line %d has %d columns", line, i)
+		}
+		_, size := utf8.DecodeRune(data[offset:end])
+		offset += size
+	}
+	return offset, nil
+}
+
+// lineRange returns the byte range of a 1-based line of the input, without its line break
+func (jp *JSONProcessor) lineRange(line int) (start, end int) {
+	data := jp.inputText()
+	lines := jp.lineStarts()
+	start, end = lines[line-1], len(data)
+	if line < len(lines) {
+		end = lines[line] - 1
+	}
+	return start, end
+}
+
+// nodeLocation returns the place of the value at node in the input
+// nodes that do not stand for a single value of the input, such as array lengths, columns and
+// the nodes of diff mode and of the union of JSON Lines records, have no location
+func (m *Model) nodeLocation(node *TreeNode) (sourceLocation, bool) {
+	if node == nil || m.diff != nil || m.recordView != viewRecord {
+		return sourceLocation{}, false
+	}
+	doc := m.source()
+	var loc sourceLocation
+	switch {
+	case doc.format == formatYAML:
+		yn := doc.yamlNodes[node.key]
+		if yn == nil || len(node.path) == 0 || yn.Line == 0 {
+			return sourceLocation{}, false
+		}
+		start, err := m.jp.lineOffset(yn.Line, yn.Column)
+		if err != nil {
+			return sourceLocation{}, false
+		}
+		_, end := m.jp.lineRange(yn.Line)
+		loc = sourceLocation{start: start, end: end}
+	case node.span.end > 0:
+		loc.start = m.jp.toSource(doc.base + node.span.start)
+		loc.end = m.jp.toSourceEnd(doc.base + node.span.end)
+	default:
+		return sourceLocation{}, false
+	}
+	loc.line, loc.col = m.jp.position(loc.start)
+	return loc, true
+}
+
+// selectedLocation returns the place of the selected node in the input
+func (m *Model) selectedLocation() (sourceLocation, bool) {
+	if m.selectedIdx < 0 || m.selectedIdx >= len(m.rows) {
+		return sourceLocation{}, false
+	}
+	return m.nodeLocation(m.rows[m.selectedIdx])
+}
+
+// toggleSourceView switches the extractor between the value and the input around it
+func (m *Model) toggleSourceView() {
+	if m.diff != nil {
+		m.status = "the source view is not available in diff mode"
+		return
+	}
+	m.sourceView = !m.sourceView
+	m.updateExtractContent()
+}
+
+// showSource shows the input around the selected node in the extractor, its value highlighted
+func (m *Model) showSource() {
+	loc, ok := m.selectedLocation()
+	if !ok {
+		loc = sourceLocation{line: 1, col: 1}
+	}
+	lines := m.jp.lineStarts()
+	first := max(loc.line-sourceContext, 1)
+	last := min(first+extractMaxLines-1, len(lines))
+	gutter := len(strconv.Itoa(last))
+
+	var b strings.Builder
+	for line := first; line <= last; line++ {
+		start, end := m.jp.lineRange(line)
+		fmt.Fprintf(&b, "%s%s\n", sourceGutterStyle.Render(fmt.Sprintf("%*d │ ", gutter, line)), m.sourceLine(start, end, loc))
+	}
+	m.setExtractContent(strings.TrimSuffix(b.String(), "\n"))
+	m.extractViewport.SetYOffset(max(loc.line-first-3, 0))
+}
+
+// sourceLine renders the input from start to end, the part inside the location highlighted
+// long lines are cut to sourceLineWidth bytes, starting shortly before the location on its line
+func (m *Model) sourceLine(start, end int, loc sourceLocation) string {
+	data := m.jp.inputText()
+	from, to := start, end
+	if loc.end > 0 && loc.start >= start && loc.start <= end && loc.start-start > sourceLineWidth/2 {
+		from = loc.start - sourceLineWidth/8
+	}
+	to = min(to, from+sourceLineWidth)
+	// cut at character boundaries
+	for from > start && !utf8.RuneStart(data[from]) {
+		from--
+	}
+	for to < end && !utf8.RuneStart(data[to]) {
+		to--
+	}
+
+	text := func(a, b int) string {
+		s := strings.TrimRight(string(data[a:b]), "\r")
+		return strings.ReplaceAll(strings.ToValidUTF8(s, "�"), "\t", "    ")
+	}
+	var b strings.Builder
+	if from > start {
+		b.WriteString("…")
+	}
+	if hlFrom, hlTo := max(from, loc.start), min(to, loc.end); loc.end > 0 && hlFrom < hlTo {
+		b.WriteString(text(from, hlFrom))
+		b.WriteString(sourceSpanStyle.Render(text(hlFrom, hlTo)))
+		b.WriteString(text(hlTo, to))
+	} else {
+		b.WriteString(text(from, to))
+	}
+	if to < end {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// startGotoLine opens the prompt for the input line to select the node of
+func (m *Model) startGotoLine() {
+	if m.diff != nil {
+		m.status = "going to a line is not available in diff mode"
+		return
+	}
+	m.edit = &editPrompt{op: editLine, prevCursor: m.searchCursor}
+	m.searchCursor = 0
+}
+
+// gotoLine selects the innermost node at a line of the input, text is "line" or "line:column"
+func (m *Model) gotoLine(text string) error {
+	lineText, colText, _ := strings.Cut(strings.TrimSpace(text), ":")
+	line, err := strconv.Atoi(lineText)
+	col := 0
+	if err == nil && colText != "" {
+		col, err = strconv.Atoi(colText)
+	}
+	if err != nil || line < 1 || col < 0 {
+		return fmt.Errorf("invalid line %q, want line or line:column", text)
+	}
+	offset, err := m.jp.lineOffset(line, col)
+	if err != nil {
+		return err
+	}
+
+	record, path, ok := m.pathAt(offset, line, col)
+	if !ok {
+		return fmt.Errorf("no value at line %d", line)
+	}
+	if len(m.jp.records) > 0 && (m.recordView != viewRecord || m.recordIdx != record) {
+		m.showRecord(record)
+	}
+	for ; len(path) > 0; path = path[:len(path)-1] {
+		if target := m.root.locate(path); target != nil {
+			m.showNode(target)
+			return nil
+		}
+	}
+	return fmt.Errorf("no node at line %d", line)
+}
+
+// pathAt returns the record and the path of the innermost value at an offset of the input
+func (m *Model) pathAt(offset, line, col int) (record int, path jsonPath, ok bool) {
+	if m.jp.format == formatYAML {
+		return m.yamlPathAt(line, col)
+	}
+	offset = m.jp.fromSource(offset)
+	data := m.jp.jsonData
+	if len(m.jp.records) > 0 {
+		record = sort.Search(len(m.jp.records), func(i int) bool { return m.jp.records[i].base > offset }) - 1
+		if record < 0 {
+			return 0, nil, false
+		}
+		data = m.jp.records[record].jsonData
+		offset -= m.jp.records[record].base
+	}
+	path = valuePathAt(gjson.ParseBytes(data), offset)
+	return record, path, path != nil
+}
+
+// valuePathAt descends from value into the member or element holding offset
+// an offset on the key of a member stands for the member
+func valuePathAt(value gjson.Result, offset int) jsonPath {
+	path := jsonPath{}
+	for value.IsObject() || value.IsArray() {
+		var next gjson.Result
+		var step jsonPath
+		value.ForEach(func(key, val gjson.Result) bool {
+			end := val.Index + len(val.Raw)
+			if value.IsObject() && key.Index > 0 && offset >= key.Index && offset < end ||
+				offset >= val.Index && offset < end {
+				next = val
+				if value.IsObject() {
+					step = path.child(key.String())
+				} else {
+					step = path.element(int(key.Int()))
+				}
+				return false
+			}
+			return val.Index <= offset
+		})
+		if step == nil {
+			break
+		}
+		value, path = next, step
+	}
+	if len(path) == 0 {
+		return nil
+	}
+	return path
+}
+
+// yamlPathAt returns the record and the path of the innermost YAML node starting at or before line:col
+func (m *Model) yamlPathAt(line, col int) (record int, path jsonPath, ok bool) {
+	docs := m.jp.records
+	if len(docs) == 0 {
+		docs = []*JSONProcessor{m.jp}
+	}
+	bestLine, bestCol := 0, 0
+	for i, doc := range docs {
+		for key, yn := range doc.yamlNodes {
+			if key == "" || yn.Line > line || yn.Line == line && col > 0 && yn.Column > col {
+				continue
+			}
+			p, err := parsePath(key)
+			if err != nil {
+				continue
+			}
+			// the node starting last is the innermost, of nodes starting together the deeper one
+			if yn.Line > bestLine || yn.Line == bestLine && (yn.Column > bestCol || yn.Column == bestCol && len(p) > len(path)) {
+				record, path, bestLine, bestCol = i, p, yn.Line, yn.Column
+			}
+		}
+	}
+	return record, path, path != nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// sourceModel returns a model showing the input file name holding data
+func sourceModel(t *testing.T, name, data string) Model {
+	t.Helper()
+	path := writeInput(t, name, data)
+	jp, err := loadInput(loadOptions{path: path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jp.extractKeys()
+	return NewBubbleteaModel(jp, path)
+}
+
+// locationOf returns the location of the node at key
+func locationOf(t *testing.T, m Model, key string) string {
+	t.Helper()
+	for _, row := range m.rows {
+		if row.key == key {
+			loc, ok := m.nodeLocation(row)
+			if !ok {
+				return ""
+			}
+			return loc.String()
+		}
+	}
+	t.Fatalf("no row %q in %q", key, rowKeys(m))
+	return ""
+}
+
+func TestLineOffset(t *testing.T) {
+	jp := &JSONProcessor{jsonData: []byte("{\n  \"名前\": \"x\",\n  \"b\": 1\n}")}
+	tests := []struct {
+		line, col int
+		want      int
+		wantErr   bool
+	}{
+		{1, 1, 0, false},
+		{2, 0, 4, false},
+		{2, 3, 4, false},
+		{2, 5, 8, false},
+		{4, 1, 28, false},
+		{5, 1, 0, true},
+		{1, 3, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := jp.lineOffset(tt.line, tt.col)
+		if (err != nil) != tt.wantErr || err == nil && got != tt.want {
+			t.Errorf("lineOffset(%d, %d) = %d, %v, want %d", tt.line, tt.col, got, err, tt.want)
+			continue
+		}
+		if err == nil && tt.col > 0 {
+			if line, col := jp.position(got); line != tt.line || col != tt.col {
+				t.Errorf("position(%d) = %d:%d, want %d:%d", got, line, col, tt.line, tt.col)
+			}
+		}
+	}
+}
+
+func TestNodeLocation(t *testing.T) {
+	tests := []struct {
+		name, data string
+		key, want  string
+	}{
+		{"a.json", "{\n  \"a\": {\"b\": [1, 2]}\n}", "a.b", "line 2, column 14, byte 15"},
+		{"a.jsonc", "{\n  // comment\n  a: 'x',\n  b: 0x10,\n}", "b", "line 4, column 6, byte 30"},
+		{"a.yaml", "a:\n  b: 1\n  c: [x, y]\n", "a.c", "line 3, column 6, byte 15"},
+	}
+	for _, tt := range tests {
+		m := sourceModel(t, tt.name, tt.data)
+		m.root.setExpandedAll(true)
+		m.updateRows()
+		if got := locationOf(t, m, tt.key); got != tt.want {
+			t.Errorf("%s: location of %s = %q, want %q", tt.name, tt.key, got, tt.want)
+		}
+	}
+
+	// JSON Lines records count from the start of the file
+	m := sourceModel(t, "a.jsonl", "{\"a\":1}\n{\"a\":2}\n")
+	m.showRecord(1)
+	if got := locationOf(t, m, "a"); got != "line 2, column 6, byte 13" {
+		t.Errorf("location in record 2 = %q", got)
+	}
+	if !strings.Contains(ansi.Strip(m.renderFooter()), "line 2, column 6") {
+		t.Errorf("footer %q does not show the location", ansi.Strip(m.renderFooter()))
+	}
+}
+
+func TestGotoLine(t *testing.T) {
+	m := sourceModel(t, "a.json", "{\n  \"a\": {\n    \"b\": [\n      1,\n      2\n    ]\n  }\n}\n")
+	tests := []struct {
+		text, want string
+	}{
+		{"5", "a.b[1]"},
+		{"3", "a.b"},
+		{"3:5", "a.b"},
+		{"2", "a"},
+	}
+	for _, tt := range tests {
+		if err := m.gotoLine(tt.text); err != nil {
+			t.Errorf("gotoLine(%q): %v", tt.text, err)
+			continue
+		}
+		if got := m.rows[m.selectedIdx].key; got != tt.want {
+			t.Errorf("gotoLine(%q) selected %s, want %s", tt.text, got, tt.want)
+		}
+	}
+	for _, text := range []string{"x", "0", "1:x", "20"} {
+		if err := m.gotoLine(text); err == nil {
+			t.Errorf("gotoLine(%q) did not fail", text)
+		}
+	}
+
+	// alt+l opens the prompt, enter selects the value
+	m = send(m, tea.KeyPressMsg{Code: 'l', Mod: tea.ModAlt})
+	if m.edit == nil || m.edit.op != editLine {
+		t.Fatalf("alt+l opened %v", m.edit)
+	}
+	m = typeText(m, "4")
+	m = send(m, tea.KeyPressMsg{Code: tea.KeyEnter})
+	if got := m.rows[m.selectedIdx].key; m.edit != nil || got != "a.b[0]" {
+		t.Errorf("alt+l selected %s, prompt %v, status %q", got, m.edit, m.status)
+	}
+}
+
+func TestGotoLineRecords(t *testing.T) {
+	m := sourceModel(t, "a.jsonl", "{\"a\":1}\n{\"a\":2,\"b\":3}\n")
+	if err := m.gotoLine("2:9"); err != nil {
+		t.Fatal(err)
+	}
+	if m.recordIdx != 1 || m.rows[m.selectedIdx].key != "b" {
+		t.Errorf("selected %s of record %d, want b of record 1", m.rows[m.selectedIdx].key, m.recordIdx)
+	}
+
+	m = sourceModel(t, "a.yaml", "a: 1\nb:\n  c: 2\n")
+	if err := m.gotoLine("3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.rows[m.selectedIdx].key; got != "b.c" {
+		t.Errorf("YAML line 3 selected %s, want b.c", got)
+	}
+}
+
+func TestSourceView(t *testing.T) {
+	m := sourceModel(t, "a.jsonc", "{\n  // comment\n  a: 'x',\n  b: 0x10,\n}")
+	m = send(m, tea.KeyPressMsg{Code: tea.KeyDown})
+	m = send(m, tea.KeyPressMsg{Code: 's', Mod: tea.ModAlt})
+	if !m.sourceView {
+		t.Fatal("alt+s did not show the source")
+	}
+	content := ansi.Strip(m.extractRaw)
+	for _, want := range []string{"1 │ {", "2 │   // comment", "4 │   b: 0x10,"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("source view %q does not show %q", content, want)
+		}
+	}
+	if got := m.sourceLine(28, 36, sourceLocation{start: 31, end: 35}); !strings.Contains(got, sourceSpanStyle.Render("0x10")) {
+		t.Errorf("line 4 = %q, want 0x10 highlighted", got)
+	}
+
+	m = send(m, tea.KeyPressMsg{Code: 's', Mod: tea.ModAlt})
+	if m.sourceView {
+		t.Error("a second alt+s did not go back to the value")
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/tidwall/gjson"
+)
+
+// tableCellWidth is the maximum width of a table column
+const tableCellWidth = 30
+
+// tableSeparator separates the columns of the table
+const tableSeparator = " │ "
+
+// valueColumn is the column holding array elements that are not objects
+const valueColumn = "(value)"
+
+var (
+	tableHeaderStyle   = lipgloss.NewStyle().Foreground(tnBlue).Bold(true)
+	tableCursorStyle   = lipgloss.NewStyle().Foreground(tnBg).Background(tnBlue).Bold(true)
+	tableSelectedStyle = lipgloss.NewStyle().Background(tnSelection)
+)
+
+// tableView is the state of the table shown in the extractor for an array of objects
+type tableView struct {
+	path     jsonPath
+	columns  []string // shown columns in display order
+	hidden   []string // hidden columns, in the order they were hidden
+	rows     []tableRow
+	skipped  int // elements left out after extractMaxLines rows
+	row, col int // selected row and column
+
+	// sortCol is the column the rows are sorted by, empty for document order
+	sortCol  string
+	sortDesc bool
+}
+
+// tableRow is one array element, its cells keyed by column
+type tableRow struct {
+	index int
+	cells map[string]gjson.Result
+}
+
+// newTableView builds the table of an array, or returns nil when no element is an object
+func newTableView(path jsonPath, array gjson.Result) *tableView {
+	t := &tableView{path: path}
+	objects := false
+	seen := make(map[string]struct{})
+	array.ForEach(func(index, elem gjson.Result) bool {
+		if len(t.rows) == extractMaxLines {
+			t.skipped++
+			return true
+		}
+		row := tableRow{index: int(index.Int()), cells: make(map[string]gjson.Result)}
+		if elem.IsObject() {
+			objects = true
+			elem.ForEach(func(key, val gjson.Result) bool {
+				if _, ok := seen[key.String()]; !ok {
+					seen[key.String()] = struct{}{}
+					t.columns = append(t.columns, key.String())
+				}
+				row.cells[key.String()] = val
+				return true
+			})
+		} else {
+			if _, ok := seen[valueColumn]; !ok {
+				seen[valueColumn] = struct{}{}
+				t.columns = append(t.columns, valueColumn)
+			}
+			row.cells[valueColumn] = elem
+		}
+		t.rows = append(t.rows, row)
+		return true
+	})
+	if !objects {
+		return nil
+	}
+	return t
+}
+
+// cellText renders a value on a single line, strings without quotes and containers compacted
+func cellText(v gjson.Result) string {
+	if !v.Exists() {
+		return ""
+	}
+	if v.Type == gjson.String {
+		return strings.Join(strings.Fields(v.String()), " ")
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, []byte(v.Raw)) == nil {
+		return compact.String()
+	}
+	return v.Raw
+}
+
+// fitCell pads or truncates text to width cells
+func fitCell(text string, width int) string {
+	text = ansi.Truncate(text, width, "…")
+	return text + strings.Repeat(" ", width-ansi.StringWidth(text))
+}
+
+// sort orders the rows by the sort column, numbers by value and missing cells last
+func (t *tableView) sort() {
+	selected := t.rows[t.row].index
+	sort.SliceStable(t.rows, func(i, j int) bool {
+		if t.sortCol == "" {
+			return t.rows[i].index < t.rows[j].index
+		}
+		a, b := t.rows[i].cells[t.sortCol], t.rows[j].cells[t.sortCol]
+		if !a.Exists() || !b.Exists() {
+			return a.Exists() && !b.Exists()
+		}
+		c := strings.Compare(cellText(a), cellText(b))
+		if a.Type == gjson.Number && b.Type == gjson.Number {
+			c = cmp.Compare(a.Num, b.Num)
+		}
+		if t.sortDesc {
+			return c > 0
+		}
+		return c < 0
+	})
+	for i, row := range t.rows {
+		if row.index == selected {
+			t.row = i
+		}
+	}
+}
+
+// render lays out the table, returning the header and the rows separately
+// so that the header stays visible while the rows scroll; spans holds the cells covered by each column
+func (t *tableView) render() (header, body string, spans [][2]int) {
+	indexWidth := 0
+	for _, row := range t.rows {
+		indexWidth = max(indexWidth, len(fmt.Sprintf("[%d]", row.index)))
+	}
+	widths := make([]int, len(t.columns))
+	for i, col := range t.columns {
+		widths[i] = ansi.StringWidth(col) + 2 // room for the sort arrow
+		for _, row := range t.rows {
+			widths[i] = max(widths[i], ansi.StringWidth(cellText(row.cells[col])))
+		}
+		widths[i] = min(widths[i], tableCellWidth)
+	}
+
+	var head, rule strings.Builder
+	head.WriteString(fitCell("#", indexWidth))
+	rule.WriteString(strings.Repeat("─", indexWidth))
+	x := indexWidth
+	for i, col := range t.columns {
+		title := col
+		if col == t.sortCol && t.sortDesc {
+			title += " ▼"
+		} else if col == t.sortCol {
+			title += " ▲"
+		}
+		style := tableHeaderStyle
+		if i == t.col {
+			style = tableCursorStyle
+		}
+		head.WriteString(tableSeparator + style.Render(fitCell(title, widths[i])))
+		rule.WriteString("─┼─" + strings.Repeat("─", widths[i]))
+		x += len([]rune(tableSeparator))
+		spans = append(spans, [2]int{x, x + widths[i]})
+		x += widths[i]
+	}
+
+	var b strings.Builder
+	for r, row := range t.rows {
+		line := fitCell(fmt.Sprintf("[%d]", row.index), indexWidth)
+		for i, col := range t.columns {
+			line += tableSeparator + fitCell(cellText(row.cells[col]), widths[i])
+		}
+		if r == t.row {
+			line = tableSelectedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	if t.skipped > 0 {
+		fmt.Fprintf(&b, "… %d more elements, open a range of the array to see them\n", t.skipped)
+	}
+	return head.String() + "\n" + rule.String(), strings.TrimSuffix(b.String(), "\n"), spans
+}
+
+// tableContent shows the selected array as a table and reports whether it is one
+func (m *Model) tableContent(node *TreeNode) bool {
+	if !m.tableMode || node.kind != kindArray || m.recordView != viewRecord || m.diff != nil {
+		return false
+	}
+	fresh := m.table == nil || !slices.Equal(m.table.path, node.path)
+	if fresh {
+		m.table = newTableView(node.path, lookup(m.jsonData, node.path))
+		if m.table == nil {
+			return false
+		}
+	}
+	t := m.table
+	if len(t.columns) == 0 {
+		return false
+	}
+	t.col = min(t.col, len(t.columns)-1)
+
+	header, body, spans := t.render()
+	vp := &m.extractViewport
+	y, x := vp.YOffset(), vp.XOffset()
+	m.tableHeader = header
+	m.setExtractContent(body)
+	if !fresh {
+		vp.SetYOffset(y)
+		vp.SetXOffset(x)
+	}
+
+	// keep the selected row and column in view
+	if t.row < vp.YOffset() {
+		vp.SetYOffset(t.row)
+	} else if t.row >= vp.YOffset()+vp.Height() {
+		vp.SetYOffset(t.row - vp.Height() + 1)
+	}
+	if span := spans[t.col]; span[0] < vp.XOffset() {
+		vp.SetXOffset(span[0])
+	} else if span[1] > vp.XOffset()+vp.Width() {
+		vp.SetXOffset(span[1] - vp.Width())
+	}
+	return true
+}
+
+// toggleTable switches the table view of arrays of objects on or off
+func (m *Model) toggleTable() {
+	m.tableMode = !m.tableMode
+	m.updateExtractContent()
+	switch {
+	case !m.tableMode:
+		m.status = "table view off"
+	case m.tableHeader == "":
+		m.status = "table view on, select an array of objects"
+	default:
+		m.status = "table view on"
+	}
+}
+
+// updateTableKeys handles a key press on the table shown in the focused extractor
+// it reports false for keys that are not table commands
+func (m *Model) updateTableKeys(key string) bool {
+	t := m.table
+	page := max(m.extractViewport.Height()-1, 1)
+	switch key {
+	case "up", "k":
+		t.row = max(t.row-1, 0)
+	case "down", "j":
+		t.row = min(t.row+1, len(t.rows)-1)
+	case "pgup", "b":
+		t.row = max(t.row-page, 0)
+	case "pgdown", "space", " ":
+		t.row = min(t.row+page, len(t.rows)-1)
+	case "g", "home":
+		t.row = 0
+	case "G", "end":
+		t.row = len(t.rows) - 1
+	case "left", "h":
+		t.col = max(t.col-1, 0)
+	case "right", "l":
+		t.col = min(t.col+1, len(t.columns)-1)
+	case "s":
+		// ascending, descending, then back to document order
+		col := t.columns[t.col]
+		switch {
+		case t.sortCol != col:
+			t.sortCol, t.sortDesc = col, false
+		case !t.sortDesc:
+			t.sortDesc = true
+		default:
+			t.sortCol = ""
+		}
+		t.sort()
+	case "x":
+		if len(t.columns) == 1 {
+			m.status = "the last column cannot be hidden"
+			return true
+		}
+		t.hidden = append(t.hidden, t.columns[t.col])
+		t.columns = slices.Delete(t.columns, t.col, t.col+1)
+		t.col = min(t.col, len(t.columns)-1)
+	case "u":
+		t.columns = append(t.columns, t.hidden...)
+		t.hidden = nil
+	case "<":
+		if t.col > 0 {
+			t.columns[t.col-1], t.columns[t.col] = t.columns[t.col], t.columns[t.col-1]
+			t.col--
+		}
+	case ">":
+		if t.col < len(t.columns)-1 {
+			t.columns[t.col+1], t.columns[t.col] = t.columns[t.col], t.columns[t.col+1]
+			t.col++
+		}
+	case "enter":
+		target := m.root.locate(t.path.element(t.rows[t.row].index))
+		if target == nil {
+			m.status = "element not found in the tree"
+			return true
+		}
+		m.focus = focusTree
+		m.showNode(target)
+		return true
+	default:
+		return false
+	}
+	m.updateExtractContent()
+	return true
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/tidwall/gjson"
+)
+
+const tableDoc = `{"users":[{"name":"cy","age":30},{"name":"ann","age":4,"tags":["a","b"]},{"name":"bob"}],"ids":[1,2]}`
+
+func TestNewTableView(t *testing.T) {
+	table := newTableView(nil, gjson.Parse(`[{"a":1,"b":{"x":[1, 2]}},7,{"c":"multi\n line"}]`))
+	if table == nil {
+		t.Fatal("no table for an array of objects")
+	}
+	if want := []string{"a", "b", valueColumn, "c"}; !slices.Equal(table.columns, want) {
+		t.Errorf("columns = %v, want %v", table.columns, want)
+	}
+	var cells []string
+	for _, row := range table.rows {
+		for _, col := range table.columns {
+			cells = append(cells, cellText(row.cells[col]))
+		}
+	}
+	want := []string{"1", `{"x":[1,2]}`, "", "", "", "", "7", "", "", "", "", "multi line"}
+	if !slices.Equal(cells, want) {
+		t.Errorf("cells = %q, want %q", cells, want)
+	}
+
+	if newTableView(nil, gjson.Parse(`[1,2,"x"]`)) != nil {
+		t.Error("a table was built for an array without objects")
+	}
+}
+
+func TestFitCell(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"ab", 4, "ab  "},
+		{"abcdef", 4, "abc…"},
+		{"名前", 5, "名前 "},
+		{"名前です", 5, "名前…"},
+	}
+	for _, tt := range tests {
+		if got := fitCell(tt.text, tt.width); got != tt.want {
+			t.Errorf("fitCell(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+// tableIndexes returns the element index of every table row
+func tableIndexes(table *tableView) []int {
+	var indexes []int
+	for _, row := range table.rows {
+		indexes = append(indexes, row.index)
+	}
+	return indexes
+}
+
+func TestTableKeys(t *testing.T) {
+	m := send(newTestModel(t, tableDoc), tea.WindowSizeMsg{Width: 120, Height: 30})
+	m = send(m, ctrlKey('t'))
+	if !m.tableMode || m.status != "table view on" || m.table == nil || !strings.Contains(m.tableHeader, "name") || !strings.Contains(m.tableHeader, "age") {
+		t.Fatalf("header = %q", m.tableHeader)
+	}
+
+	// sorting by age: ascending with missing cells last, descending, then document order
+	m = typeText(send(m, tea.KeyPressMsg{Code: tea.KeyTab}), "ls")
+	if got := tableIndexes(m.table); !slices.Equal(got, []int{1, 0, 2}) || !strings.Contains(m.tableHeader, "age ▲") {
+		t.Errorf("ascending order = %v, header %q", got, m.tableHeader)
+	}
+	if m = typeText(m, "s"); !slices.Equal(tableIndexes(m.table), []int{0, 1, 2}) {
+		t.Errorf("descending order = %v", tableIndexes(m.table))
+	}
+	if m = typeText(m, "s"); !slices.Equal(tableIndexes(m.table), []int{0, 1, 2}) || m.table.sortCol != "" {
+		t.Errorf("document order = %v", tableIndexes(m.table))
+	}
+
+	// hiding, restoring and moving columns
+	if m = typeText(m, "x"); !slices.Equal(m.table.columns, []string{"name", "tags"}) {
+		t.Errorf("columns after x = %v", m.table.columns)
+	}
+	if m = typeText(m, "u"); !slices.Equal(m.table.columns, []string{"name", "tags", "age"}) {
+		t.Errorf("columns after u = %v", m.table.columns)
+	}
+	if m = typeText(m, "<"); !slices.Equal(m.table.columns, []string{"tags", "name", "age"}) || m.table.col != 0 {
+		t.Errorf("columns after < = %v, selected %d", m.table.columns, m.table.col)
+	}
+
+	// enter selects the element of the row in the tree
+	m = send(typeText(m, "jj"), tea.KeyPressMsg{Code: tea.KeyEnter})
+	if got := m.rows[m.selectedIdx].key; got != "users[2]" || m.focus != focusTree {
+		t.Errorf("enter selected %s, focus %d", got, m.focus)
+	}
+
+	// arrays without objects keep the JSON view
+	m.selectNode(m.root.locate(jsonPath(nil).child("ids")))
+	if m.tableHeader != "" {
+		t.Error("a table is shown for an array of numbers")
+	}
+	if m = send(m, ctrlKey('t'), ctrlKey('t')); m.status != "table view on, select an array of objects" {
+		t.Errorf("ctrl+t on ids: status %q", m.status)
+	}
+}
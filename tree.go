@@ -0,0 +1,343 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/tidwall/gjson"
+)
+
+// previewWidth is the maximum number of characters of a scalar shown next to its node
+const previewWidth = 24
+
+// TreeNode represents a node in the collapsible JSON tree
+// key is the rendered path, used for lookups, search and display
+type TreeNode struct {
+	path     jsonPath
+	key      string
+	name     string
+	depth    int
+	parent   *TreeNode
+	children []*TreeNode
+	expanded bool
+
+	// filter is set while the node is kept by the active filter
+	filter *filterMatch
+
+	// values holds the scalar value of a leaf, one per record for JSON Lines input
+	values []string
+
+	// status marks the node as added, removed, changed or unchanged in diff mode
+	status diffStatus
+
+	// lazy holds the documents a bucket of a large array loads its elements from when first expanded
+	lazy []*JSONProcessor
+
+	// kind is the JSON type of the value, size the number of members or elements of a container
+	// and preview the raw text of a scalar; JSON Lines nodes describe the first record holding the key
+	kind    valueKind
+	size    int
+	preview string
+
+	// span is the range of the value in its document, of the first record holding the key for JSON Lines
+	span sourceSpan
+
+	// column is set on the nodes projecting a member over the elements of an array
+	column *columnStats
+
+	// violations lists the schema keywords the value fails, nestedViolations counts those of its descendants
+	violations       []violation
+	nestedViolations int
+}
+
+// valueKind is the JSON type of the value at a node
+type valueKind int
+
+const (
+	kindNone   valueKind = iota // no value, e.g. the "#" length node or a record list row
+	kindObject                  // {...}
+	kindArray                   // [...]
+	kindString                  // "..."
+	kindNumber                  // 1.5
+	kindBool                    // true, false
+	kindNull                    // null
+	kindMixed                   // different types across JSON Lines records
+)
+
+// kindOf returns the kind of a value
+func kindOf(v gjson.Result) valueKind {
+	switch {
+	case v.IsObject():
+		return kindObject
+	case v.IsArray():
+		return kindArray
+	}
+	switch v.Type {
+	case gjson.String:
+		return kindString
+	case gjson.Number:
+		return kindNumber
+	case gjson.True, gjson.False:
+		return kindBool
+	case gjson.Null:
+		return kindNull
+	}
+	return kindNone
+}
+
+// label returns the name of the kind shown in the tree
+func (k valueKind) label() string {
+	switch k {
+	case kindObject:
+		return "object"
+	case kindArray:
+		return "array"
+	case kindString:
+		return "string"
+	case kindNumber:
+		return "number"
+	case kindBool:
+		return "bool"
+	case kindNull:
+		return "null"
+	case kindMixed:
+		return "mixed"
+	}
+	return ""
+}
+
+// style returns the color of the kind
+func (k valueKind) style() lipgloss.Style {
+	switch k {
+	case kindObject:
+		return lipgloss.NewStyle().Foreground(tnBlue)
+	case kindArray:
+		return lipgloss.NewStyle().Foreground(tnPurple)
+	case kindString:
+		return lipgloss.NewStyle().Foreground(tnGreen)
+	case kindNumber:
+		return lipgloss.NewStyle().Foreground(tnYellow)
+	case kindBool:
+		return lipgloss.NewStyle().Foreground(tnCyan)
+	case kindNull, kindMixed:
+		return lipgloss.NewStyle().Foreground(tnComment)
+	}
+	return lipgloss.NewStyle()
+}
+
+// describe records the kind, size and preview of a value at the node
+// a node seen with different kinds, e.g. across JSON Lines records, becomes kindMixed
+func (n *TreeNode) describe(v gjson.Result) {
+	kind := kindOf(v)
+	if kind == kindNone {
+		return
+	}
+	if n.kind != kindNone && n.kind != kind {
+		n.kind, n.preview = kindMixed, ""
+		return
+	}
+	first := n.kind == kindNone
+	n.kind = kind
+	switch kind {
+	case kindObject, kindArray:
+		size := 0
+		v.ForEach(func(_, _ gjson.Result) bool {
+			size++
+			return true
+		})
+		n.size = max(n.size, size)
+	default:
+		if first {
+			n.preview = truncatePreview(v.Raw)
+		}
+	}
+}
+
+// truncatePreview shortens a scalar to previewWidth characters on a single line
+func truncatePreview(raw string) string {
+	raw = strings.Join(strings.Fields(raw), " ")
+	if runes := []rune(raw); len(runes) > previewWidth {
+		return string(runes[:previewWidth-1]) + "…"
+	}
+	return raw
+}
+
+// badge returns the kind of the node with its size or preview, e.g. array[3] or string "foo"
+// the preview is left out when withPreview is false
+func (n *TreeNode) badge(withPreview bool) string {
+	if n.column != nil {
+		return n.column.badge()
+	}
+	switch n.kind {
+	case kindNone:
+		return ""
+	case kindObject:
+		return fmt.Sprintf("%s{%d}", n.kind.label(), n.size)
+	case kindArray:
+		return fmt.Sprintf("%s[%d]", n.kind.label(), n.size)
+	case kindNull, kindMixed:
+		return n.kind.label()
+	}
+	if !withPreview {
+		return n.kind.label()
+	}
+	return n.kind.label() + " " + n.preview
+}
+
+// hasChildren reports whether the node can be expanded or collapsed
+func (n *TreeNode) hasChildren() bool {
+	return len(n.children) > 0 || len(n.lazy) > 0
+}
+
+// setExpandedAll expands or collapses the node and all of its descendants
+// buckets that were never opened stay collapsed so that huge arrays are not loaded at once
+func (n *TreeNode) setExpandedAll(expanded bool) {
+	n.expanded = expanded && n.lazy == nil
+	for _, child := range n.children {
+		child.setExpandedAll(expanded)
+	}
+}
+
+// expandToDepth expands nodes shallower than depth and collapses the rest
+// depth 0 shows only the top-level keys
+func (n *TreeNode) expandToDepth(depth int) {
+	n.expanded = n.depth < depth && n.lazy == nil
+	for _, child := range n.children {
+		child.expandToDepth(depth)
+	}
+}
+
+// reveal expands every ancestor of the node so that it becomes visible
+func (n *TreeNode) reveal() {
+	for p := n.parent; p != nil; p = p.parent {
+		p.expanded = true
+	}
+}
+
+// buildTree builds the node tree of a processor in document order
+// JSON Lines records are merged into a single tree holding the union of their keys,
+// only paths contained in jp.keys become nodes so that the tree and the search index agree
+func buildTree(jp *JSONProcessor) *TreeNode {
+	keySet := make(map[string]struct{}, len(jp.keys))
+	for _, key := range jp.keys {
+		keySet[key.String()] = struct{}{}
+	}
+
+	root := &TreeNode{depth: -1, expanded: true}
+	b := &treeBuilder{keySet: keySet, nodes: make(map[string]*TreeNode)}
+	if len(jp.records) == 0 {
+		b.doc = jp
+		b.walk(root, nil, gjson.ParseBytes(jp.jsonData))
+	}
+	for _, record := range jp.records {
+		b.doc = record
+		b.walk(root, nil, gjson.ParseBytes(record.jsonData))
+	}
+	return root
+}
+
+// treeBuilder adds the nodes of one or more documents to a tree
+type treeBuilder struct {
+	keySet map[string]struct{} // paths that become nodes, nil for every path
+	nodes  map[string]*TreeNode
+	doc    *JSONProcessor // document being walked, loaded again by the buckets of large arrays
+}
+
+// add returns the node of path below parent, creating it on first sight
+func (b *treeBuilder) add(parent *TreeNode, path jsonPath, name string) *TreeNode {
+	key := path.String()
+	if _, ok := b.keySet[key]; b.keySet != nil && !ok {
+		return nil
+	}
+	if node, dup := b.nodes[key]; dup {
+		return node
+	}
+	node := &TreeNode{
+		path:     path,
+		key:      key,
+		name:     name,
+		depth:    parent.depth + 1,
+		parent:   parent,
+		expanded: true,
+	}
+	b.nodes[key] = node
+	parent.children = append(parent.children, node)
+	return node
+}
+
+// walk adds the members or elements of value below parent
+// arrays longer than arrayBucketSize get collapsed buckets instead of their elements
+func (b *treeBuilder) walk(parent *TreeNode, prefix jsonPath, value gjson.Result) {
+	if parent.depth >= 0 {
+		parent.describe(value)
+		if parent.span.end == 0 && value.Index > 0 {
+			parent.span = sourceSpan{start: value.Index, end: value.Index + len(value.Raw)}
+		}
+	}
+	if value.IsObject() {
+		value.ForEach(func(key, val gjson.Result) bool {
+			fullKey := prefix.child(key.String())
+			if node := b.add(parent, fullKey, key.String()); node != nil {
+				b.walk(node, fullKey, val)
+			}
+			return true
+		})
+	} else if value.IsArray() {
+		b.add(parent, prefix.count(), "#")
+		b.addColumns(parent, prefix, value)
+		if length := arrayLength(value); length > arrayBucketSize {
+			b.addBuckets(parent, prefix, length)
+			return
+		}
+		value.ForEach(func(index, val gjson.Result) bool {
+			elementKey := prefix.element(int(index.Int()))
+			if node := b.add(parent, elementKey, fmt.Sprintf("[%d]", index.Int())); node != nil {
+				b.walk(node, elementKey, val)
+			}
+			return true
+		})
+	} else if parent.depth >= 0 {
+		parent.values = append(parent.values, value.String())
+	}
+}
+
+// flattenTree returns the rows visible under the current expansion state
+// when keep is non-nil only the nodes it holds (as matches or as their ancestors) are listed,
+// siblings ordered by their best match score and then by document order
+func flattenTree(root *TreeNode, keep *filterMatch) []*TreeNode {
+	var rows []*TreeNode
+	if keep != nil {
+		var walk func(f *filterMatch)
+		walk = func(f *filterMatch) {
+			for _, child := range f.children {
+				rows = append(rows, child.node)
+				if child.node.expanded {
+					walk(child)
+				}
+			}
+		}
+		walk(keep)
+		return rows
+	}
+	var walk func(n *TreeNode)
+	walk = func(n *TreeNode) {
+		for _, child := range n.children {
+			rows = append(rows, child)
+			if child.expanded {
+				child.loadBucket()
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+	return rows
+}
+
+// walkTree calls fn for every node below root in document order
+func walkTree(root *TreeNode, fn func(n *TreeNode)) {
+	for _, child := range root.children {
+		fn(child)
+		walkTree(child, fn)
+	}
+}
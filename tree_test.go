@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+const treeDoc = `{"name":"a","tags":["x","y"],"meta":{"owner":{"id":1}}}`
+
+func TestBuildTree(t *testing.T) {
+	m := newTestModel(t, treeDoc)
+	var nodes []string
+	walkTree(m.root, func(n *TreeNode) {
+		nodes = append(nodes, fmt.Sprintf("%d %s %s", n.depth, n.key, n.name))
+	})
+	want := []string{
+		"0 name name",
+		"0 tags tags",
+		"1 tags.# #",
+		"1 tags[0] [0]",
+		"1 tags[1] [1]",
+		"0 meta meta",
+		"1 meta.owner owner",
+		"2 meta.owner.id id",
+	}
+	if !slices.Equal(nodes, want) {
+		t.Errorf("tree = %q, want %q", nodes, want)
+	}
+}
+
+func TestTreeFolding(t *testing.T) {
+	left := tea.KeyPressMsg{Code: tea.KeyLeft}
+	right := tea.KeyPressMsg{Code: tea.KeyRight}
+	down := tea.KeyPressMsg{Code: tea.KeyDown}
+	tests := []struct {
+		name string
+		keys []tea.Msg
+		want []string
+	}{
+		{"expanded", nil, []string{"name", "tags", "tags.#", "tags[0]", "tags[1]", "meta", "meta.owner", "meta.owner.id"}},
+		{"collapse all", []tea.Msg{tea.KeyPressMsg{Code: tea.KeyLeft, Mod: tea.ModShift}}, []string{"name", "tags", "meta"}},
+		{"expand to depth", []tea.Msg{tea.KeyPressMsg{Code: '1', Mod: tea.ModAlt}}, []string{"name", "tags", "tags.#", "tags[0]", "tags[1]", "meta", "meta.owner"}},
+		{"collapse the selected node", []tea.Msg{down, left}, []string{"name", "tags", "meta", "meta.owner", "meta.owner.id"}},
+		{"collapse, then select the parent", []tea.Msg{down, down, left, left}, []string{"name", "tags", "meta", "meta.owner", "meta.owner.id"}},
+		{"expand again", []tea.Msg{down, left, right}, []string{"name", "tags", "tags.#", "tags[0]", "tags[1]", "meta", "meta.owner", "meta.owner.id"}},
+	}
+	for _, tt := range tests {
+		m := send(newTestModel(t, treeDoc), tt.keys...)
+		if got := rowKeys(m); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: rows = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// right on an expanded node moves to its first child, left on a leaf to its parent
+	m := send(newTestModel(t, treeDoc), down, right)
+	if key := m.rows[m.selectedIdx].key; key != "tags.#" {
+		t.Errorf("right selected %s, want tags.#", key)
+	}
+	m = send(m, left)
+	if key := m.rows[m.selectedIdx].key; key != "tags" {
+		t.Errorf("left selected %s, want tags", key)
+	}
+}
+
+func TestNodeBadge(t *testing.T) {
+	m := newTestModel(t, `{"spec":{"replicas":3,"paused":false,"image":null,"containers":[{"name":"web"},{"name":"a very long container name that is cut"}]},"empty":{}}`)
+	badges := make(map[string]string)
+	walkTree(m.root, func(n *TreeNode) {
+		badges[n.key] = n.badge(true)
+	})
+	want := map[string]string{
+		"spec":                    "object{4}",
+		"spec.replicas":           "number 3",
+		"spec.paused":             "bool false",
+		"spec.image":              "null",
+		"spec.containers":         "array[2]",
+		"spec.containers.#":       "",
+		"spec.containers[0].name": `string "web"`,
+		"spec.containers[1].name": `string "a very long container …`,
+		"empty":                   "object{0}",
+	}
+	for key, badge := range want {
+		if badges[key] != badge {
+			t.Errorf("badge of %s = %q, want %q", key, badges[key], badge)
+		}
+	}
+
+	// value search shows the matching snippet instead of the preview
+	m = typeText(send(m, ctrlKey('s')), "web")
+	row := m.formatTreeItemPlain(m.rows[m.selectedIdx], false)
+	if !strings.HasSuffix(row, "name string: web") {
+		t.Errorf("value search row = %q", row)
+	}
+}
+
+func TestNodeKindAcrossRecords(t *testing.T) {
+	m := newTestModel(t, "{\"id\":1,\"tags\":[\"a\"]}\n{\"id\":\"x\",\"tags\":[\"b\",\"c\"]}\n")
+	m = send(m, ctrlKey('l'))
+	badges := make(map[string]string)
+	walkTree(m.root, func(n *TreeNode) {
+		badges[n.key] = n.badge(true)
+	})
+	// keys holding different types are mixed, containers report their largest size
+	if badges["id"] != "mixed" || badges["tags"] != "array[2]" {
+		t.Errorf("union badges = %v", badges)
+	}
+}
+
+func TestTruncatePreview(t *testing.T) {
+	tests := []struct {
+		raw, want string
+	}{
+		{`"short"`, `"short"`},
+		{"\"multi\n  line\"", `"multi line"`},
+		{`"exactly twenty-four ch"`, `"exactly twenty-four ch"`},
+		{`"twenty-five characters.."`, `"twenty-five characters…`},
+		{`"日本語のとても長い文字列はここで切られてしまいます"`, `"日本語のとても長い文字列はここで切られてしま…`},
+	}
+	for _, tt := range tests {
+		if got := truncatePreview(tt.raw); got != tt.want {
+			t.Errorf("truncatePreview(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
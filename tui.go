@@ -2,11 +2,19 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"charm.land/bubbles/v2/progress"
+	"charm.land/bubbles/v2/spinner"
 	"charm.land/bubbles/v2/viewport"
 	tea "charm.land/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/tidwall/gjson"
 )
 
 // Tokyo Night color palette
@@ -18,8 +26,10 @@ const (
 	tnCyan      = lipgloss.Color("#7dcfff") // cyan
 	tnGreen     = lipgloss.Color("#9ece6a") // green
 	tnYellow    = lipgloss.Color("#e0af68") // yellow
+	tnRed       = lipgloss.Color("#f7768e") // red
 	tnBorder    = lipgloss.Color("#3b4261") // border
 	tnSelection = lipgloss.Color("#283457") // selection
+	tnComment   = lipgloss.Color("#565f89") // comment
 )
 
 // Styles
@@ -47,14 +57,14 @@ var (
 	selectedItemStyle = lipgloss.NewStyle().
 				Foreground(tnCyan).
 				Bold(true)
-)
 
-// TreeItem represents an item in the JSON tree
-type TreeItem struct {
-	key     string
-	display string
-	depth   int
-}
+	contextItemStyle = lipgloss.NewStyle().
+				Foreground(tnComment)
+
+	statusStyle = lipgloss.NewStyle().
+			Foreground(tnComment).
+			Padding(0, 1)
+)
 
 // Model represents the application state
 type Model struct {
@@ -66,69 +76,292 @@ type Model struct {
 	jp *JSONProcessor
 
 	// Tree state
-	treeItems   []TreeItem
+	root        *TreeNode
+	rows        []*TreeNode
 	selectedIdx int
 
 	// Search state
+	// filterRoot links the matching nodes and their ancestors; nil when not filtering
+	// filter keeps the last result so that typing narrows it instead of scanning the tree again
 	searchQuery  string
 	searchCursor int
-	filteredKeys []string
+	filterRoot   *filterMatch
+	filter       *filterCache
+
+	// filterJob is the filter being scanned while typing, filterID identifies the latest one
+	// opened lists the buckets search opened, shared by the copies of the model
+	filterJob *filterJob
+	filterID  int
+	opened    *openedBuckets
+
+	// Query state
+	searchMode searchMode
+	valueQuery string
+	jqQuery    string
+	jqResult   string
+
+	// Extractor shows YAML instead of JSON
+	yamlOutput bool
+
+	// typeModel is the type model shown in the extractor instead of the value, empty for the value
+	typeModel string
+
+	// sourceView shows the input around the selected node in the extractor instead of its value
+	sourceView bool
+
+	// hideComments hides the comments of JSONC / JSON5 members in the extractor
+	hideComments bool
+
+	// violationIdx is the schema error last selected with alt+n / alt+p, counting from 1
+	violationIdx int
+
+	// Node picked with enter, printed after the TUI exits
+	picked *Selection
+
+	// JSON Lines state
+	recordView recordView
+	recordIdx  int
+
+	// Diff mode state, nil when a single document is explored
+	diff *diffView
+
+	// Editing state, edit is the open prompt and dirty marks unsaved changes
+	edit  *editPrompt
+	dirty bool
+
+	// status is a message shown next to the search bar until the next key press,
+	// statusID identifies the latest one for clearing it after a timeout
+	status   string
+	statusID int
+
+	// copyPending is set after ctrl+x until the key choosing what to copy is pressed
+	copyPending bool
+
+	// Extractor state, focus is the panel receiving navigation keys and extractRaw the
+	// content before find highlighting; findIdx indexes the current match of findQuery
+	focus       panelFocus
+	extractRaw  string
+	findQuery   string
+	findMatches []findMatch
+	findIdx     int
+
+	// typing defers extractor renders while the search input changes, extractDue marks the
+	// content as out of date until the render scheduled with extractID runs
+	typing     bool
+	extractDue bool
+	extractID  int
+
+	// Table state, tableMode shows arrays of objects as a table whose header is kept
+	// above the scrolling rows in tableHeader, empty while no table is shown
+	tableMode   bool
+	table       *tableView
+	tableHeader string
+
+	// Loading state, the input is loaded in the background while the TUI is shown
+	loader       *loader
+	loadProgress loadProgress
+	loadErr      error
+	loadSpinner  spinner.Model
+	loadBar      progress.Model
 
 	// UI state
-	width       int
-	height      int
-	leftWidth   int
-	rightWidth  int
-	ready       bool
+	width      int
+	height     int
+	leftWidth  int
+	rightWidth int
+	ready      bool
 
 	// Viewports
 	treeViewport    viewport.Model
+	treeOffset      int // first row shown in the tree panel
 	extractViewport viewport.Model
 }
 
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
+	if m.loader != nil {
+		return tea.Batch(m.loadSpinner.Tick, m.loader.wait())
+	}
 	return nil
 }
 
 // Update handles messages and updates the model
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.loader != nil {
+		return m.updateLoading(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		warned := m.status == quitWarning
+		m.status = ""
+
+		// while an edit prompt is open only its input is edited
+		if m.edit != nil {
+			switch msg.String() {
+			case "enter":
+				m.submitEdit()
+				return m, nil
+			case "esc":
+				find := m.edit.op == editFind
+				m.closeEdit()
+				if find {
+					m.setFind("")
+				}
+				return m, nil
+			case "ctrl+c", "backspace", "ctrl+h", "ctrl+a", "ctrl+e", "ctrl+b", "ctrl+f", "ctrl+k":
+			default:
+				if msg.Key().Text == "" {
+					return m, nil
+				}
+			}
+		}
+
+		if m.copyPending {
+			m.copyPending = false
+			return m, m.copySelection(msg.String())
+		}
+
+		if m.focus == focusExtract && m.edit == nil {
+			if handled, cmd := m.updateExtractKeys(msg); handled {
+				return m, cmd
+			}
+		}
+
 		switch msg.String() {
 		case "ctrl+c":
+			if m.dirty && !warned {
+				m.status = quitWarning
+				break
+			}
 			return m, tea.Quit
 
+		case "tab":
+			if m.focus == focusTree {
+				m.focus = focusExtract
+			} else {
+				m.focus = focusTree
+			}
+
 		case "up", "ctrl+p":
 			if m.selectedIdx > 0 {
-				m.selectedIdx--
-				if m.selectedIdx >= 0 && m.selectedIdx < len(m.filteredKeys) {
-					m.searchQuery = m.filteredKeys[m.selectedIdx]
-					m.searchCursor = len(m.searchQuery)
-				}
-				m.updateTreeContent()
-				m.updateExtractContent()
+				m.selectRow(m.selectedIdx - 1)
 			}
 
 		case "down", "ctrl+n":
-			if m.selectedIdx < len(m.filteredKeys)-1 {
-				m.selectedIdx++
-				if m.selectedIdx >= 0 && m.selectedIdx < len(m.filteredKeys) {
-					m.searchQuery = m.filteredKeys[m.selectedIdx]
-					m.searchCursor = len(m.searchQuery)
-				}
-				m.updateTreeContent()
-				m.updateExtractContent()
+			if m.selectedIdx < len(m.rows)-1 {
+				m.selectRow(m.selectedIdx + 1)
+			}
+
+		// Tree folding
+		case "left":
+			m.collapseOrSelectParent()
+
+		case "right":
+			if m.recordView == viewRecords {
+				m.openSelectedRecord()
+				break
 			}
+			m.expandOrSelectChild()
+
+		case "shift+left":
+			m.root.setExpandedAll(false)
+			m.root.expanded = true
+			m.updateRows()
+
+		case "shift+right":
+			m.root.setExpandedAll(true)
+			m.updateRows()
+
+		case "alt+1", "alt+2", "alt+3", "alt+4", "alt+5", "alt+6", "alt+7", "alt+8", "alt+9":
+			m.root.expandToDepth(int(msg.String()[4] - '0'))
+			m.updateRows()
 
 		case "enter":
-			// no-op: selection already tracked by selectedIdx
+			if m.recordView == viewRecords {
+				m.openSelectedRecord()
+				break
+			}
+			if m.dirty && !warned {
+				m.status = quitWarning
+				break
+			}
+			if sel := m.currentSelection(); sel != nil {
+				m.picked = sel
+				return m, tea.Quit
+			}
+
+		case "ctrl+s":
+			return m, m.cycleSearchMode()
+
+		case "ctrl+y":
+			m.yamlOutput = !m.yamlOutput
+			m.updateExtractContent()
+
+		case "ctrl+x":
+			m.copyPending = true
+			m.status = copyPrompt
+
+		// Editing
+		case "ctrl+v":
+			m.startEdit(editValue)
+
+		case "ctrl+r":
+			m.startEdit(editRename)
+
+		case "ctrl+o":
+			m.startEdit(editAddKey)
+
+		case "ctrl+d":
+			m.deleteSelected()
+
+		case "ctrl+w":
+			m.saveInput()
+
+		case "ctrl+g":
+			m.startJump()
+
+		case "ctrl+t":
+			m.toggleTable()
+
+		case "alt+e":
+			m.startExport()
+
+		case "alt+m":
+			m.cycleTypeModel()
+
+		case "alt+c":
+			m.toggleComments()
+
+		case "alt+s":
+			m.toggleSourceView()
+
+		case "alt+l":
+			m.startGotoLine()
+
+		case "alt+n":
+			m.nextViolation(1)
+
+		case "alt+p":
+			m.nextViolation(-1)
+
+		// JSON Lines navigation
+		case "ctrl+l":
+			m.cycleRecordView()
+
+		case "alt+up":
+			m.showRecord(m.recordIdx - 1)
+
+		case "alt+down":
+			m.showRecord(m.recordIdx + 1)
 
 		case "backspace", "ctrl+h":
-			if len(m.searchQuery) > 0 && m.searchCursor > 0 {
-				m.searchQuery = m.searchQuery[:m.searchCursor-1] + m.searchQuery[m.searchCursor:]
-				m.searchCursor--
-				m.updateFilteredKeys()
+			q := m.inputText()
+			if m.searchCursor > 0 {
+				_, size := utf8.DecodeLastRuneInString((*q)[:m.searchCursor])
+				*q = (*q)[:m.searchCursor-size] + (*q)[m.searchCursor:]
+				m.searchCursor -= size
+				return m, m.onInputChanged()
 			}
 
 		// Emacs keybindings for search input
@@ -136,38 +369,54 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.searchCursor = 0
 
 		case "ctrl+e":
-			m.searchCursor = len(m.searchQuery)
+			m.searchCursor = len(*m.inputText())
 
 		case "ctrl+b":
 			if m.searchCursor > 0 {
-				m.searchCursor--
+				_, size := utf8.DecodeLastRuneInString((*m.inputText())[:m.searchCursor])
+				m.searchCursor -= size
 			}
 
 		case "ctrl+f":
-			if m.searchCursor < len(m.searchQuery) {
-				m.searchCursor++
+			q := m.inputText()
+			if m.searchCursor < len(*q) {
+				_, size := utf8.DecodeRuneInString((*q)[m.searchCursor:])
+				m.searchCursor += size
 			}
 
 		case "ctrl+k":
-			m.searchQuery = m.searchQuery[:m.searchCursor]
-			m.updateFilteredKeys()
+			q := m.inputText()
+			*q = (*q)[:m.searchCursor]
+			return m, m.onInputChanged()
 
 		default:
 			// Handle character input for search
-			if len(msg.String()) == 1 {
-				char := msg.String()[0]
-				if (char >= 'a' && char <= 'z') ||
-					(char >= 'A' && char <= 'Z') ||
-					(char >= '0' && char <= '9') ||
-					char == '.' || char == '[' || char == ']' ||
-					char == '_' || char == '#' {
-					m.searchQuery = m.searchQuery[:m.searchCursor] + msg.String() + m.searchQuery[m.searchCursor:]
-					m.searchCursor++
-					m.updateFilteredKeys()
-				}
+			text := msg.Key().Text
+			if text != "" {
+				q := m.inputText()
+				*q = (*q)[:m.searchCursor] + text + (*q)[m.searchCursor:]
+				m.searchCursor += len(text)
+				return m, m.onInputChanged()
 			}
 		}
 
+	case clearStatusMsg:
+		if msg.id == m.statusID {
+			m.status = ""
+		}
+
+	case filterStepMsg:
+		// steps of a filter replaced by a later key press are dropped
+		if m.filterJob != nil && msg.id == m.filterJob.id && m.filterJob.root == m.root {
+			return m, m.stepFilter()
+		}
+
+	case renderExtractMsg:
+		// renders scheduled before the last key press are dropped
+		if msg.id == m.extractID && m.extractDue {
+			m.updateExtractContent()
+		}
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -176,13 +425,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.calculateTreeWidth()
 
 		if !m.ready {
-			m.treeViewport = viewport.New(viewport.WithWidth(m.leftWidth-4), viewport.WithHeight(m.height-8))
-			m.extractViewport = viewport.New(viewport.WithWidth(m.rightWidth-4), viewport.WithHeight(m.height-8))
+			m.treeViewport = viewport.New(viewport.WithWidth(m.leftWidth-8), viewport.WithHeight(m.height-8))
+			m.extractViewport = viewport.New(viewport.WithWidth(m.rightWidth-8), viewport.WithHeight(m.height-8))
+			m.extractViewport.SetHorizontalStep(extractScrollStep)
 			m.ready = true
 		} else {
-			m.treeViewport.SetWidth(m.leftWidth - 4)
+			m.treeViewport.SetWidth(m.leftWidth - 8)
 			m.treeViewport.SetHeight(m.height - 8)
-			m.extractViewport.SetWidth(m.rightWidth - 4)
+			m.extractViewport.SetWidth(m.rightWidth - 8)
 			m.extractViewport.SetHeight(m.height - 8)
 		}
 
@@ -195,6 +445,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // View renders the UI
 func (m Model) View() tea.View {
+	if m.loader != nil {
+		v := tea.NewView(m.renderLoading())
+		v.AltScreen = true
+		return v
+	}
 	if !m.ready {
 		return tea.NewView("Initializing...")
 	}
@@ -212,10 +467,15 @@ func (m Model) View() tea.View {
 	v := tea.NewView(content)
 	v.AltScreen = true
 
+	// the search bar is not edited while the extractor has focus
+	if m.focus == focusExtract && m.edit == nil {
+		return v
+	}
+
 	// Position terminal cursor at search bar input position.
-	// searchStyle has Padding(0, 1), so text starts at X=1.
-	// "Search: " is 8 chars, so cursor X = 1 + 8 + searchCursor.
-	cursorX := 1 + 8 + m.searchCursor
+	// searchStyle has Padding(0, 1), so text starts at X=1,
+	// followed by the mode label such as "Search: ".
+	cursorX := 1 + lipgloss.Width(m.inputLabel()) + lipgloss.Width((*m.inputText())[:m.searchCursor])
 	cursorY := strings.Count(header, "\n") + 1 + strings.Count(main, "\n") + 1
 	v.Cursor = &tea.Cursor{
 		Position: tea.Position{X: cursorX, Y: cursorY},
@@ -228,7 +488,28 @@ func (m Model) View() tea.View {
 
 // renderHeader renders the header with filename
 func (m Model) renderHeader() string {
-	return headerStyle.Render(fmt.Sprintf("File: %s", m.fileName))
+	if m.diff != nil {
+		return headerStyle.Render(fmt.Sprintf("Diff: %s → %s · %s", m.diff.oldName, m.diff.newName, m.diff.summary()))
+	}
+	header := fmt.Sprintf("File: %s", m.fileName)
+	if m.dirty {
+		header += " · modified"
+	}
+	if records := len(m.jp.records); records > 0 {
+		noun := "record"
+		if m.jp.format == formatYAML {
+			noun = "document"
+		}
+		switch m.recordView {
+		case viewRecords:
+			header += fmt.Sprintf(" · %d %ss", records, noun)
+		case viewUnion:
+			header += fmt.Sprintf(" · all keys of %d %ss", records, noun)
+		default:
+			header += fmt.Sprintf(" · %s %d/%d", noun, m.recordIdx+1, records)
+		}
+	}
+	return headerStyle.Render(header)
 }
 
 // renderMain renders the main content (left and right panels)
@@ -248,7 +529,7 @@ func (m Model) renderTreePanel() string {
 	title := lipgloss.NewStyle().
 		Foreground(tnBlue).
 		Bold(true).
-		Render("JSON Tree")
+		Render(m.recordView.title())
 
 	content := m.treeViewport.View()
 
@@ -258,7 +539,11 @@ func (m Model) renderTreePanel() string {
 		content,
 	)
 
-	return treeStyle.
+	style := treeStyle
+	if m.focus == focusTree {
+		style = style.BorderForeground(tnBlue)
+	}
+	return style.
 		Width(m.leftWidth - 4).
 		Height(m.height - 8).
 		Render(panel)
@@ -269,9 +554,18 @@ func (m Model) renderExtractPanel() string {
 	title := lipgloss.NewStyle().
 		Foreground(tnBlue).
 		Bold(true).
-		Render("JSON Extractor")
+		Render(m.extractTitle())
 
 	content := m.extractViewport.View()
+	if m.tableHeader != "" {
+		// the header scrolls horizontally with the rows
+		x := m.extractViewport.XOffset()
+		var header []string
+		for _, line := range strings.Split(m.tableHeader, "\n") {
+			header = append(header, ansi.Cut(line, x, x+m.extractViewport.Width()))
+		}
+		content = strings.Join(header, "\n") + "\n" + content
+	}
 
 	panel := lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -279,111 +573,519 @@ func (m Model) renderExtractPanel() string {
 		content,
 	)
 
-	return extractStyle.
+	style := extractStyle
+	if m.focus == focusExtract {
+		style = style.BorderForeground(tnBlue)
+	}
+	return style.
 		Width(m.rightWidth - 4).
 		Height(m.height - 8).
 		Render(panel)
 }
 
-// renderFooter renders the search bar
+// extractTitle returns the extractor panel title including the output format, wrapping and find query
+func (m Model) extractTitle() string {
+	title := "JSON Extractor"
+	switch {
+	case m.typeModel != "" && m.diff == nil:
+		title += " · " + typeModelLabel(m.typeModel)
+	case m.yamlOutput:
+		title += " · YAML"
+	}
+	if m.extractViewport.SoftWrap {
+		title += " · wrap"
+	}
+	if m.findQuery != "" {
+		title += fmt.Sprintf(" · find %q", m.findQuery)
+	}
+	return title
+}
+
+// renderFooter renders the search bar and the status message
 func (m Model) renderFooter() string {
-	searchText := fmt.Sprintf("Search: %s", m.searchQuery)
-	return searchStyle.Render(searchText)
+	searchText := m.inputLabel() + *m.inputText()
+	footer := searchStyle.Render(searchText)
+	if m.status != "" {
+		footer += statusStyle.Render(m.status)
+	} else if loc, ok := m.selectedLocation(); ok {
+		footer += statusStyle.Render(loc.String())
+	}
+	return footer
+}
+
+// inputLabel returns the prompt of the search bar or of the open edit prompt
+func (m *Model) inputLabel() string {
+	if m.edit != nil {
+		return m.edit.label()
+	}
+	return m.searchMode.label()
+}
+
+// Selection is the node or jq expression picked with enter
+type Selection struct {
+	key    string
+	query  bool           // key is a jq expression
+	source *JSONProcessor // document or JSON Lines records the key applies to
+}
+
+// currentSelection returns what enter would pick, or nil when nothing is selected
+func (m *Model) currentSelection() *Selection {
+	if m.searchMode == searchJQ {
+		return &Selection{key: m.jqQuery, query: true, source: m.source()}
+	}
+	if m.selectedIdx >= 0 && m.selectedIdx < len(m.rows) {
+		return &Selection{key: m.rows[m.selectedIdx].key, source: m.source()}
+	}
+	return nil
 }
 
-// updateFilteredKeys updates the filtered keys based on search query
+// searchMode selects how the search bar input is interpreted
+type searchMode int
+
+const (
+	searchKeys   searchMode = iota // fuzzy match against key paths
+	searchValues                   // substring match against leaf values
+	searchJQ                       // evaluate a jq expression
+)
+
+// label returns the search bar prompt for the mode
+func (s searchMode) label() string {
+	switch s {
+	case searchValues:
+		return "Value: "
+	case searchJQ:
+		return "jq: "
+	default:
+		return "Search: "
+	}
+}
+
+// inputText returns the search bar buffer edited in the current mode, or the edit prompt input
+func (m *Model) inputText() *string {
+	if m.edit != nil {
+		return &m.edit.text
+	}
+	switch m.searchMode {
+	case searchValues:
+		return &m.valueQuery
+	case searchJQ:
+		return &m.jqQuery
+	default:
+		return &m.searchQuery
+	}
+}
+
+// onInputChanged refreshes the tree or the query result after the search bar was edited
+// it returns the command rendering the extractor once typing pauses, nil when it is up to date
+func (m *Model) onInputChanged() tea.Cmd {
+	if m.edit != nil {
+		if m.edit.op == editFind {
+			m.setFind(m.edit.text)
+		}
+		return nil
+	}
+	if m.searchMode == searchJQ {
+		m.updateQueryResult()
+		return nil
+	}
+	m.filterJob = m.startFilter()
+	return m.stepFilter()
+}
+
+// cycleSearchMode switches the search bar between key search, value search and jq query mode
+func (m *Model) cycleSearchMode() tea.Cmd {
+	switch m.searchMode {
+	case searchKeys:
+		m.searchMode = searchValues
+	case searchValues:
+		m.searchMode = searchJQ
+		// jq queries do not filter the tree, drop the value filter
+		m.clearFilter()
+		m.filter = nil
+		m.updateRows()
+	default:
+		m.searchMode = searchKeys
+	}
+	m.searchCursor = len(*m.inputText())
+	return m.onInputChanged()
+}
+
+// updateQueryResult evaluates the jq expression and shows its output in the extractor
+func (m *Model) updateQueryResult() {
+	result, err := m.source().runJQ(m.jqQuery)
+	if err != nil {
+		m.jqResult = fmt.Sprintf("jq: %v", err)
+	} else {
+		m.jqResult = highlightJSON(result)
+	}
+	m.updateExtractContent()
+}
+
+// updateFilteredKeys updates the filtered tree rows based on search query, scanning the tree at once
 func (m *Model) updateFilteredKeys() {
-	if m.searchQuery == "" {
-		m.filteredKeys = make([]string, len(m.jp.keys))
-		copy(m.filteredKeys, m.jp.keys)
+	m.filterJob = m.startFilter()
+	if m.filterJob.match != nil {
+		m.filterJob.scan(time.Time{})
+	}
+	m.applyFilter()
+}
+
+// startFilter prepares filtering the tree by the search query
+// key search ranks the matches by their fuzzy score and selects the best one,
+// in value search mode the rows are filtered by their leaf values instead of their keys
+func (m *Model) startFilter() *filterJob {
+	var match func(n *TreeNode) (score int, ok bool)
+	query := m.searchQuery
+	switch {
+	case m.searchMode == searchValues && m.valueQuery != "":
+		query = m.valueQuery
+		needle := foldRunes(query)
+		match = func(n *TreeNode) (int, bool) {
+			return 0, n.hasValue(needle)
+		}
+	case m.searchMode != searchValues && m.searchQuery != "":
+		// positions are only needed for the rows on screen, formatTreeItem finds them
+		matcher := newFuzzyMatcher(query)
+		match = func(n *TreeNode) (int, bool) {
+			score, _, ok := matcher.match(n.key, false)
+			return score, ok
+		}
+	}
+	if m.opened.root != m.root {
+		*m.opened = openedBuckets{root: m.root}
+	}
+	if match == nil {
+		return &filterJob{root: m.root, opened: m.opened}
+	}
+	m.filterID++
+	job := m.newFilterJob(m.searchMode, query, match)
+	job.id = m.filterID
+	return job
+}
+
+// stepFilter scans for one time slice and applies the filter once it is done
+// it returns the command continuing the scan, or rendering the extractor once typing pauses
+func (m *Model) stepFilter() tea.Cmd {
+	job := m.filterJob
+	if job.match != nil && !job.done {
+		start := time.Now()
+		job.done = job.scan(start.Add(filterSlice))
+		// a scan that used up most of its slice leaves applying the result to the next step
+		if !job.done || time.Since(start) > filterSlice/2 {
+			return func() tea.Msg { return filterStepMsg{id: job.id} }
+		}
+	}
+	m.typing = true
+	m.applyFilter()
+	m.typing = false
+	if m.extractDue {
+		return m.scheduleExtract()
+	}
+	return nil
+}
+
+// applyFilter shows the result of the filter that finished scanning, keeping the best matches
+// and their ancestors in the tree
+func (m *Model) applyFilter() {
+	job := m.filterJob
+	m.filterJob = nil
+	var best *TreeNode
+	bestScore := 0
+	m.clearFilter()
+	if job.match == nil {
+		m.filter = nil
 	} else {
-		m.filteredKeys = []string{}
-		for _, key := range m.jp.keys {
-			if fuzzyFind(key, m.searchQuery) {
-				m.filteredKeys = append(m.filteredKeys, key)
+		// matches are added in document order, so that siblings of equal score keep it
+		kept := slices.SortedFunc(slices.Values(job.top), func(a, b scoredNode) int { return a.order - b.order })
+		m.filterRoot = m.keepNode(m.root, 0)
+		for _, s := range kept {
+			m.keepNode(s.node, s.score).matched = true
+			if best == nil || s.score > bestScore {
+				best, bestScore = s.node, s.score
 			}
 		}
+		if len(kept) > 0 {
+			m.filterRoot.rank()
+		}
+		m.filter = &filterCache{root: m.root, mode: job.mode, query: job.query, matches: job.matches}
+		if job.matches.len() > len(kept) {
+			m.status = fmt.Sprintf("showing %d of %d matches", len(kept), job.matches.len())
+		}
 	}
+	m.closeBuckets(job)
 
-	// Sort filtered keys to group children of the same parent together
-	sortTreeKeys(m.filteredKeys)
+	m.updateRows()
+	if best != nil {
+		for i, row := range m.rows {
+			if row == best {
+				m.selectedIdx = i
+				m.updateTreeContent()
+				m.updateExtractContent()
+				break
+			}
+		}
+	}
+}
+
+// updateRows recomputes the visible rows and keeps the current selection when possible
+func (m *Model) updateRows() {
+	var selected *TreeNode
+	if m.selectedIdx >= 0 && m.selectedIdx < len(m.rows) {
+		selected = m.rows[m.selectedIdx]
+	}
 
-	if len(m.filteredKeys) == 0 {
-		m.selectedIdx = -1
-	} else if m.selectedIdx >= len(m.filteredKeys) {
+	m.rows = flattenTree(m.root, m.filterRoot)
+
+	// fall back to the nearest visible ancestor when the selected node was folded away,
+	// found in a single pass over the rows
+	m.selectedIdx = -1
+	ancestors := make(map[*TreeNode]int)
+	for n, depth := selected, 0; n != nil; n, depth = n.parent, depth+1 {
+		ancestors[n] = depth
+	}
+	nearest := len(ancestors)
+	for i, row := range m.rows {
+		if depth, ok := ancestors[row]; ok && depth < nearest {
+			m.selectedIdx, nearest = i, depth
+		}
+	}
+	// while filtering, move off rows that are only shown as context for a match
+	if m.filterRoot != nil && (m.selectedIdx < 0 || !m.rows[m.selectedIdx].filter.matched) {
+		for i, row := range m.rows {
+			if row.filter.matched {
+				m.selectedIdx = i
+				break
+			}
+		}
+	}
+	if m.selectedIdx < 0 && len(m.rows) > 0 {
 		m.selectedIdx = 0
 	}
 
-	// Recalculate tree width based on filtered content
-	m.calculateTreeWidth()
 	m.updateTreeContent()
 	m.updateExtractContent()
 }
 
+// selectRow selects the given row and mirrors its key in the search bar
+func (m *Model) selectRow(idx int) {
+	m.selectedIdx = idx
+	m.searchQuery = m.rows[idx].key
+	if m.searchMode == searchKeys {
+		m.searchCursor = len(m.searchQuery)
+	}
+	m.updateTreeContent()
+	m.updateExtractContent()
+}
+
+// selectNode selects the row showing the given node
+func (m *Model) selectNode(n *TreeNode) {
+	for i, row := range m.rows {
+		if row == n {
+			m.selectRow(i)
+			return
+		}
+	}
+}
+
+// showNode reveals and selects a node, adding it to an active filter so that it is listed
+func (m *Model) showNode(target *TreeNode) {
+	target.reveal()
+	if m.filterRoot != nil && target.filter == nil {
+		m.keepNode(target, 0).matched = true
+	}
+	m.updateRows()
+	for i, row := range m.rows {
+		if row == target {
+			m.selectedIdx = i
+			m.updateTreeContent()
+			m.updateExtractContent()
+			break
+		}
+	}
+}
+
+// collapseOrSelectParent collapses the selected node or moves to its parent
+func (m *Model) collapseOrSelectParent() {
+	if m.selectedIdx < 0 || m.selectedIdx >= len(m.rows) {
+		return
+	}
+	node := m.rows[m.selectedIdx]
+	if node.hasChildren() && node.expanded {
+		node.expanded = false
+		m.updateRows()
+		return
+	}
+	if node.parent != m.root {
+		m.selectNode(node.parent)
+	}
+}
+
+// expandOrSelectChild expands the selected node or moves to its first child
+func (m *Model) expandOrSelectChild() {
+	if m.selectedIdx < 0 || m.selectedIdx >= len(m.rows) {
+		return
+	}
+	node := m.rows[m.selectedIdx]
+	if !node.hasChildren() {
+		return
+	}
+	if !node.expanded {
+		node.expanded = true
+		m.updateRows()
+		return
+	}
+	if m.selectedIdx+1 < len(m.rows) && m.rows[m.selectedIdx+1].parent == node {
+		m.selectRow(m.selectedIdx + 1)
+	}
+}
+
 // updateTreeContent updates the tree viewport content
 func (m *Model) updateTreeContent() {
-	var content strings.Builder
+	// only the rows on screen are rendered and measured, the tree may hold millions
+	start, end := m.treeWindow()
+	m.calculateTreeWidth()
 
-	for i, key := range m.filteredKeys {
-		display := m.formatTreeItem(key, i == m.selectedIdx)
+	var content strings.Builder
+	for i := start; i < end; i++ {
+		display := m.formatTreeItem(m.rows[i], i == m.selectedIdx)
+		// cut rows wider than the panel instead of letting them wrap
+		if m.ready {
+			display = ansi.Truncate(display, m.treeViewport.Width(), "…")
+		}
 		content.WriteString(display)
 		content.WriteString("\n")
 	}
 
 	m.treeViewport.SetContent(content.String())
+	m.treeViewport.SetYOffset(0)
+}
 
-	// Auto-scroll to selected item
-	if m.selectedIdx >= 0 && m.selectedIdx < len(m.filteredKeys) {
-		lineHeight := 1
-		targetLine := m.selectedIdx * lineHeight
-		viewportHeight := m.treeViewport.Height()
-
+// treeWindow returns the range of rows shown in the tree panel, scrolled so that the selection is visible
+func (m *Model) treeWindow() (start, end int) {
+	height := max(m.height-8, 1)
+	if m.selectedIdx >= 0 && m.selectedIdx < len(m.rows) {
 		// Scroll down if selected item is below viewport
-		if targetLine >= m.treeViewport.YOffset()+viewportHeight {
-			m.treeViewport.SetYOffset(targetLine - viewportHeight + 1)
+		if m.selectedIdx >= m.treeOffset+height {
+			m.treeOffset = m.selectedIdx - height + 1
 		}
 		// Scroll up if selected item is above viewport
-		if targetLine < m.treeViewport.YOffset() {
-			m.treeViewport.SetYOffset(targetLine)
+		if m.selectedIdx < m.treeOffset {
+			m.treeOffset = m.selectedIdx
 		}
 	}
+	m.treeOffset = max(min(m.treeOffset, len(m.rows)-height), 0)
+	return m.treeOffset, min(m.treeOffset+height, len(m.rows))
 }
 
 // formatTreeItem formats a tree item with proper indentation and highlighting
-func (m *Model) formatTreeItem(key string, selected bool) string {
-	depth := strings.Count(key, ".") + strings.Count(key, "[")
-	indent := strings.Repeat("  ", depth)
-
-	// Determine the display symbol
-	symbol := "├─"
-
-	// Extract display name with parent context for clarity
-	displayName := getDisplayName(key)
-
-	display := fmt.Sprintf("%s%s %s", indent, symbol, displayName)
+func (m *Model) formatTreeItem(node *TreeNode, selected bool) string {
+	name := node.name
+	f := node.filter
+	if f != nil && f.matched && m.searchMode != searchValues && m.searchQuery != "" {
+		if _, positions, ok := fuzzyMatch(node.key, m.searchQuery); ok {
+			name = highlightPositions(name, namePositions(node, positions))
+		}
+	}
+	if marker := node.status.marker(); selected || (f != nil && !f.matched) {
+		name = marker + node.errorMarker(false) + name
+	} else {
+		name = node.status.style().Render(marker) + node.errorMarker(true) + name
+	}
+	snippet := m.matchSnippet(node)
+	badge := node.badge(snippet == "")
+	if snippet != "" {
+		snippet = highlightMatches(snippet, m.valueQuery)
+	}
+	// selected and context rows are styled as a whole
+	if badge != "" && !selected && (f == nil || f.matched) {
+		badge = node.kind.style().Render(badge)
+	}
+	display := formatTreeRow(node, selected, name, badge, snippet)
 
 	if selected {
-		return selectedItemStyle.Render("> " + display)
+		return selectedItemStyle.Render(display)
 	}
-	return "  " + display
+	// ancestors shown only for context while filtering are dimmed
+	if f != nil && !f.matched {
+		return contextItemStyle.Render(display)
+	}
+	return display
 }
 
-// getDisplayName extracts a meaningful display name from the full key path
-func getDisplayName(key string) string {
-	parts := strings.Split(key, ".")
-	lastPart := parts[len(parts)-1]
-	return lastPart
+// treeSymbol returns the fold marker for a node
+func treeSymbol(node *TreeNode) string {
+	if !node.hasChildren() {
+		return "├─"
+	}
+	if node.expanded {
+		return "▾ "
+	}
+	return "▸ "
 }
 
 // updateExtractContent updates the extract viewport content
 func (m *Model) updateExtractContent() {
-	if m.selectedIdx >= 0 && m.selectedIdx < len(m.filteredKeys) {
-		selectedKey := m.filteredKeys[m.selectedIdx]
-		jsonData := getParsedResult(selectedKey, m.jsonData)
+	if m.typing {
+		m.extractDue = true
+		return
+	}
+	m.extractDue = false
+	m.tableHeader = ""
+	if m.typeModel != "" && m.diff == nil {
+		m.setExtractContent(m.typeModelContent())
+		return
+	}
+	if m.searchMode == searchJQ {
+		m.setExtractContent(m.jqResult)
+		return
+	}
+	if m.sourceView && m.diff == nil {
+		m.showSource()
+		return
+	}
+	if m.selectedIdx >= 0 && m.selectedIdx < len(m.rows) {
+		selectedKey := m.rows[m.selectedIdx].key
+		if m.diff != nil {
+			content := m.diffContent(selectedKey)
+			if m.searchMode == searchValues {
+				content = highlightMatches(content, m.valueQuery)
+			}
+			m.setExtractContent(content)
+			return
+		}
+		if m.tableContent(m.rows[m.selectedIdx]) {
+			return
+		}
+		var jsonData string
+		switch {
+		case m.recordView == viewRecords && m.yamlOutput:
+			jsonData = m.jp.records[recordIndex(selectedKey)].toYAML("")
+		case m.recordView == viewRecords:
+			jsonData = formatResult(gjson.ParseBytes(m.jp.records[recordIndex(selectedKey)].jsonData))
+		case m.recordView == viewUnion:
+			jsonData = getRecordResults(selectedKey, m.jp.records, m.yamlOutput)
+		case m.rows[m.selectedIdx].column != nil && !m.yamlOutput:
+			jsonData = getColumnResults(m.rows[m.selectedIdx].path, m.jsonData)
+		case m.yamlOutput:
+			jsonData = m.source().toYAML(selectedKey)
+		default:
+			jsonData = getParsedResult(selectedKey, m.jsonData)
+		}
+		jsonData = limitLines(jsonData)
 		highlightedJSON := highlightJSON(jsonData)
-		m.extractViewport.SetContent(highlightedJSON)
+		if m.yamlOutput {
+			highlightedJSON = highlightYAML(jsonData)
+		}
+		if m.searchMode == searchValues {
+			highlightedJSON = highlightMatches(highlightedJSON, m.valueQuery)
+		}
+		highlightedJSON = m.commentReport(m.rows[m.selectedIdx]) + highlightedJSON
+		if violations := m.rows[m.selectedIdx].violations; len(violations) > 0 {
+			highlightedJSON = violationReport(violations) + highlightedJSON
+		}
+		m.setExtractContent(highlightedJSON)
 	} else {
-		m.extractViewport.SetContent("No item selected")
+		m.setExtractContent("No item selected")
 	}
 }
 
@@ -393,10 +1095,11 @@ func (m *Model) calculateTreeWidth() {
 		return
 	}
 
-	// Find maximum display width needed for tree items
+	// Find maximum display width needed for the tree items on screen
 	maxWidth := 0
-	for i, key := range m.filteredKeys {
-		display := m.formatTreeItemPlain(key, i == m.selectedIdx)
+	start, end := m.treeWindow()
+	for i := start; i < end; i++ {
+		display := m.formatTreeItemPlain(m.rows[i], i == m.selectedIdx)
 		displayWidth := lipgloss.Width(display)
 		if displayWidth > maxWidth {
 			maxWidth = displayWidth
@@ -407,7 +1110,7 @@ func (m *Model) calculateTreeWidth() {
 	neededWidth := maxWidth + 8
 
 	// Calculate percentage based on needed width
-	minWidth := int(float64(m.width) * 0.25) // 25% minimum
+	minWidth := int(float64(m.width) * 0.25)      // 25% minimum
 	maxWidthLimit := int(float64(m.width) * 0.60) // 60% maximum
 
 	// Set tree width within bounds
@@ -423,21 +1126,28 @@ func (m *Model) calculateTreeWidth() {
 
 	// Update viewport widths if they exist
 	if m.ready {
-		m.treeViewport.SetWidth(m.leftWidth - 4)
-		m.extractViewport.SetWidth(m.rightWidth - 4)
+		m.treeViewport.SetWidth(m.leftWidth - 8)
+		m.extractViewport.SetWidth(m.rightWidth - 8)
 	}
 }
 
 // formatTreeItemPlain formats a tree item without styling for width calculation
-func (m *Model) formatTreeItemPlain(key string, selected bool) string {
-	depth := strings.Count(key, ".") + strings.Count(key, "[")
-	indent := strings.Repeat("  ", depth)
-
-	symbol := "├─"
+func (m *Model) formatTreeItemPlain(node *TreeNode, selected bool) string {
+	snippet := m.matchSnippet(node)
+	return formatTreeRow(node, selected, node.status.marker()+node.errorMarker(false)+node.name, node.badge(snippet == ""), snippet)
+}
 
-	displayName := getDisplayName(key)
+// formatTreeRow lays out a tree row from its (possibly highlighted) name, kind badge and value snippet
+func formatTreeRow(node *TreeNode, selected bool, name, badge, snippet string) string {
+	indent := strings.Repeat("  ", node.depth)
 
-	display := fmt.Sprintf("%s%s %s", indent, symbol, displayName)
+	display := fmt.Sprintf("%s%s %s", indent, treeSymbol(node), name)
+	if badge != "" {
+		display += " " + badge
+	}
+	if snippet != "" {
+		display += ": " + snippet
+	}
 
 	if selected {
 		return "> " + display
@@ -445,71 +1155,183 @@ func (m *Model) formatTreeItemPlain(key string, selected bool) string {
 	return "  " + display
 }
 
-// sortTreeKeys sorts keys to group children of the same parent together
-func sortTreeKeys(keys []string) {
-	// Simple alphabetical sort will group keys with the same prefix together
-	for i := 0; i < len(keys)-1; i++ {
-		for j := i + 1; j < len(keys); j++ {
-			if keys[i] > keys[j] {
-				keys[i], keys[j] = keys[j], keys[i]
-			}
-		}
+// matchSnippet returns the matching value shown next to a node during value search
+func (m *Model) matchSnippet(node *TreeNode) string {
+	if f := node.filter; m.searchMode != searchValues || f == nil || !f.matched {
+		return ""
 	}
+	value, idx := node.matchValue(m.valueQuery)
+	if idx < 0 {
+		return ""
+	}
+	return valueSnippet(value, idx)
 }
 
-// generateTreeItems generates tree items from JSON keys
-func generateTreeItems(keys []string) []TreeItem {
-	items := make([]TreeItem, 0, len(keys))
+// NewBubbleteaModel creates a new Bubbletea model
+// JSON Lines input starts with the record list
+func NewBubbleteaModel(jp *JSONProcessor, fileName string) Model {
+	m := Model{
+		fileName:    fileName,
+		jp:          jp,
+		selectedIdx: 0,
+		searchQuery: "",
+		yamlOutput:  jp.format == formatYAML,
+		opened:      &openedBuckets{},
+	}
+	if len(jp.records) > 0 {
+		m.recordView = viewRecords
+	}
+
+	m.root = m.buildViewTree()
+	m.rows = flattenTree(m.root, nil)
+	m.status = m.schemaSummary()
+	if summary := jp.recoverySummary(); summary != "" {
+		m.status = strings.TrimSuffix(summary+"; "+m.status, "; ")
+	}
 
-	for _, key := range keys {
-		depth := strings.Count(key, ".") + strings.Count(key, "[")
-		display := formatKeyAsTree(key)
+	return m
+}
 
-		items = append(items, TreeItem{
-			key:     key,
-			display: display,
-			depth:   depth,
-		})
+// NewDiffModel creates a model comparing two documents in a merged tree
+// search, jq and enter apply to the new document
+func NewDiffModel(old, new *JSONProcessor, oldName, newName string) Model {
+	diff := newDiffView(old, new, oldName, newName)
+	m := Model{
+		fileName:   newName,
+		jp:         diff.new,
+		diff:       diff,
+		yamlOutput: new.format == formatYAML,
+		opened:     &openedBuckets{},
 	}
+	m.root = m.buildViewTree()
+	m.rows = flattenTree(m.root, nil)
 
-	return items
+	return m
 }
 
-// formatKeyAsTree formats a key as a tree-like display
-func formatKeyAsTree(key string) string {
-	depth := strings.Count(key, ".") + strings.Count(key, "[")
-	indent := strings.Repeat("  ", depth)
+// recordView selects what the tree panel shows for JSON Lines input
+type recordView int
 
-	parts := strings.Split(key, ".")
-	lastPart := parts[len(parts)-1]
+const (
+	viewRecord  recordView = iota // key tree of the current record, or of the whole document
+	viewRecords                   // list of all records
+	viewUnion                     // union of keys across all records
+)
 
-	return fmt.Sprintf("%s├─ %s", indent, lastPart)
+// title returns the tree panel title for the view
+func (v recordView) title() string {
+	switch v {
+	case viewRecords:
+		return "Records"
+	case viewUnion:
+		return "All Keys"
+	default:
+		return "JSON Tree"
+	}
 }
 
-// NewBubbleteaModel creates a new Bubbletea model
-func NewBubbleteaModel(jp *JSONProcessor, fileName string) Model {
-	// Sort keys initially
-	sortTreeKeys(jp.keys)
+// source returns the processor that keys and jq expressions are evaluated against
+func (m *Model) source() *JSONProcessor {
+	if len(m.jp.records) > 0 && m.recordView == viewRecord {
+		return m.jp.records[m.recordIdx]
+	}
+	return m.jp
+}
 
-	m := Model{
-		jsonData:     jp.jsonData,
-		fileName:     fileName,
-		jp:           jp,
-		filteredKeys: jp.keys,
-		selectedIdx:  0,
-		searchQuery:  "",
+// buildViewTree builds the tree shown for the current view and points jsonData at its document
+func (m *Model) buildViewTree() *TreeNode {
+	m.jsonData = m.source().jsonData
+	if m.diff != nil {
+		return m.diff.buildTree()
 	}
+	if m.recordView == viewRecords {
+		root := buildRecordList(m.jp.records)
+		for i, record := range m.jp.records {
+			root.children[i].nestedViolations = len(record.violations)
+		}
+		return root
+	}
+	root := buildTree(m.source())
+	markViolations(root, m.source().violations)
+	return root
+}
 
-	m.treeItems = generateTreeItems(jp.keys)
+// loadView rebuilds the tree after the view or the current record changed
+func (m *Model) loadView() {
+	m.root = m.buildViewTree()
+	m.table = nil
+	m.violationIdx = 0
+	m.rows = nil
+	m.selectedIdx = 0
+	m.searchQuery = ""
+	if m.searchMode == searchKeys {
+		m.searchCursor = 0
+	}
+	m.updateFilteredKeys()
 
-	return m
+	if m.recordView == viewRecords && m.recordIdx < len(m.rows) {
+		m.selectedIdx = m.recordIdx
+		m.updateTreeContent()
+		m.updateExtractContent()
+	}
+	if m.searchMode == searchJQ {
+		m.updateQueryResult()
+	}
 }
 
-// RunBubbleteaTUI starts the Bubbletea TUI
-func RunBubbleteaTUI(jp *JSONProcessor, fileName string) error {
-	m := NewBubbleteaModel(jp, fileName)
+// cycleRecordView switches between the record, record list and union views of JSON Lines input
+func (m *Model) cycleRecordView() {
+	if len(m.jp.records) == 0 {
+		return
+	}
+	switch m.recordView {
+	case viewRecords:
+		m.recordView = viewUnion
+	case viewUnion:
+		m.recordView = viewRecord
+	default:
+		m.recordView = viewRecords
+	}
+	m.loadView()
+}
+
+// showRecord shows the key tree of the record at idx
+func (m *Model) showRecord(idx int) {
+	if idx < 0 || idx >= len(m.jp.records) {
+		return
+	}
+	m.recordIdx = idx
+	m.recordView = viewRecord
+	m.loadView()
+}
 
-	p := tea.NewProgram(m)
-	_, err := p.Run()
-	return err
+// openSelectedRecord shows the key tree of the record selected in the record list
+func (m *Model) openSelectedRecord() {
+	if m.selectedIdx >= 0 && m.selectedIdx < len(m.rows) {
+		m.showRecord(recordIndex(m.rows[m.selectedIdx].key))
+	}
+}
+
+// RunBubbleteaTUI loads the input, starts the Bubbletea TUI and returns the selection picked with enter
+// Two inputs open diff mode. The TUI is drawn on the controlling terminal so that stdin and stdout stay free for pipes
+func RunBubbleteaTUI(inputs ...loadOptions) (*Selection, error) {
+	m := newLoadingModel(inputs)
+
+	var opts []tea.ProgramOption
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		defer func() { _ = tty.Close() }()
+		opts = append(opts, tea.WithInput(tty), tea.WithOutput(tty))
+		lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(tty))
+	}
+
+	p := tea.NewProgram(m, opts...)
+	final, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+	fm := final.(Model)
+	if fm.loadErr != nil {
+		return nil, fm.loadErr
+	}
+	return fm.picked, nil
 }
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/quick"
+	"github.com/tidwall/gjson"
+)
+
+// Type models generated from a value, also accepted by -f
+const (
+	typesGo     = "go"     // Go structs with json tags
+	typesTS     = "ts"     // TypeScript interfaces
+	typesSchema = "schema" // JSON Schema draft 2020-12
+)
+
+// schemaDialect is the $schema of the generated JSON Schema documents
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// shapeKind is a set of the JSON types seen at one place of a document
+type shapeKind uint8
+
+const (
+	shapeNull shapeKind = 1 << iota
+	shapeBool
+	shapeInt
+	shapeFloat
+	shapeString
+	shapeObject
+	shapeArray
+)
+
+// goInitialisms are written in upper case in Go names, as golint wants them
+var goInitialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "SQL": true, "TTL": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// typeShape is the type inferred from all the values merged at one place of a document
+type typeShape struct {
+	kinds shapeKind
+
+	// objects counts the merged objects, members held by fewer objects are optional
+	objects int
+	fields  []*typeField
+	index   map[string]*typeField
+
+	// elem merges the elements of all the merged arrays
+	elem *typeShape
+}
+
+// typeField is an object member and the number of objects holding it
+type typeField struct {
+	name  string
+	shape *typeShape
+	count int
+}
+
+// inferShape merges values into a single shape
+func inferShape(values []gjson.Result) *typeShape {
+	s := &typeShape{}
+	for _, v := range values {
+		s.merge(v)
+	}
+	return s
+}
+
+// merge adds a value to the shape
+func (s *typeShape) merge(v gjson.Result) {
+	switch {
+	case !v.Exists() || v.Type == gjson.Null:
+		s.kinds |= shapeNull
+	case v.Type == gjson.True, v.Type == gjson.False:
+		s.kinds |= shapeBool
+	case v.Type == gjson.Number && strings.ContainsAny(v.Raw, ".eE"):
+		s.kinds |= shapeFloat
+	case v.Type == gjson.Number:
+		s.kinds |= shapeInt
+	case v.Type == gjson.String:
+		s.kinds |= shapeString
+	case v.IsObject():
+		s.kinds |= shapeObject
+		s.objects++
+		seen := make(map[string]struct{})
+		v.ForEach(func(key, val gjson.Result) bool {
+			name := key.String()
+			if _, dup := seen[name]; dup {
+				return true
+			}
+			seen[name] = struct{}{}
+			f, ok := s.index[name]
+			if !ok {
+				if s.index == nil {
+					s.index = make(map[string]*typeField)
+				}
+				f = &typeField{name: name, shape: &typeShape{}}
+				s.index[name] = f
+				s.fields = append(s.fields, f)
+			}
+			f.count++
+			f.shape.merge(val)
+			return true
+		})
+	case v.IsArray():
+		s.kinds |= shapeArray
+		if s.elem == nil {
+			s.elem = &typeShape{}
+		}
+		v.ForEach(func(_, elem gjson.Result) bool {
+			s.elem.merge(elem)
+			return true
+		})
+	}
+}
+
+// nullable reports whether null was seen
+func (s *typeShape) nullable() bool {
+	return s.kinds&shapeNull != 0
+}
+
+// only reports whether the non-null values are all of kind, integers counting as numbers for shapeFloat
+func (s *typeShape) only(kind shapeKind) bool {
+	kinds := s.kinds &^ shapeNull
+	if kind == shapeFloat && kinds == shapeInt|shapeFloat {
+		return true
+	}
+	return kinds == kind
+}
+
+// optional reports whether the member is missing from some of the objects
+func (s *typeShape) optional(f *typeField) bool {
+	return f.count < s.objects
+}
+
+// renderTypes renders the type model of values, name is the name of the top level type
+func renderTypes(values []gjson.Result, lang, name string) (string, error) {
+	shape := inferShape(values)
+	switch lang {
+	case typesGo:
+		return newTypeNamer().goTypes(shape, name), nil
+	case typesTS:
+		return newTypeNamer().tsTypes(shape, name), nil
+	case typesSchema:
+		var compact, indented bytes.Buffer
+		shape.writeSchema(&compact, true)
+		if err := json.Indent(&indented, compact.Bytes(), "", "  "); err != nil {
+			return "", err
+		}
+		return indented.String() + "\n", nil
+	}
+	return "", fmt.Errorf("unknown type model %q", lang)
+}
+
+// typeModelLabel returns the name of a type model shown in the extractor title
+func typeModelLabel(lang string) string {
+	switch lang {
+	case typesGo:
+		return "Go"
+	case typesTS:
+		return "TypeScript"
+	default:
+		return "JSON Schema"
+	}
+}
+
+// cycleTypeModel switches the extractor between the value and its Go, TypeScript and JSON Schema type models
+func (m *Model) cycleTypeModel() {
+	switch m.typeModel {
+	case "":
+		m.typeModel = typesGo
+	case typesGo:
+		m.typeModel = typesTS
+	case typesTS:
+		m.typeModel = typesSchema
+	default:
+		m.typeModel = ""
+	}
+	m.updateExtractContent()
+}
+
+// typeModelContent renders the type model of the values shown in the extractor
+func (m *Model) typeModelContent() string {
+	results, err := m.selectionResults()
+	if err != nil {
+		return err.Error()
+	}
+	model, err := renderTypes(results, m.typeModel, m.selectionName())
+	if err != nil {
+		return err.Error()
+	}
+	lexer := "json"
+	switch m.typeModel {
+	case typesGo:
+		lexer = "go"
+	case typesTS:
+		lexer = "typescript"
+	}
+	var highlighted bytes.Buffer
+	if err := quick.Highlight(&highlighted, model, lexer, "terminal", "monokai"); err != nil {
+		return model
+	}
+	return highlighted.String()
+}
+
+// namedShape is a shape waiting to be rendered as a named type
+type namedShape struct {
+	name  string
+	shape *typeShape
+}
+
+// typeNamer hands out unique type names and queues the object shapes to render
+type typeNamer struct {
+	taken   map[string]bool
+	pending []namedShape
+}
+
+func newTypeNamer() *typeNamer {
+	return &typeNamer{taken: make(map[string]bool)}
+}
+
+// declare queues a shape under a unique name derived from key and returns the name
+func (n *typeNamer) declare(key string, shape *typeShape) string {
+	base := typeName(key)
+	name := base
+	for i := 2; n.taken[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	n.taken[name] = true
+	n.pending = append(n.pending, namedShape{name: name, shape: shape})
+	return name
+}
+
+// typeName turns a key into an exported type name, e.g. created_at becomes CreatedAt
+func typeName(key string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if upper := strings.ToUpper(word); goInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	name := b.String()
+	if name == "" {
+		return "Value"
+	}
+	// a name is only exported when it starts with an upper case letter, 名前 becomes X名前
+	switch first, _ := utf8.DecodeRuneInString(name); {
+	case unicode.IsDigit(first):
+		return "N" + name
+	case !unicode.IsUpper(first):
+		return "X" + name
+	}
+	return name
+}
+
+// singular returns the name of the elements of an array named name, e.g. Item for items
+func singular(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name + "Item"
+}
+
+// goTypes renders the shape as Go type declarations, the top level type first
+func (n *typeNamer) goTypes(shape *typeShape, name string) string {
+	var b strings.Builder
+	root := typeName(name)
+	n.taken[root] = true
+	if shape.only(shapeObject) {
+		n.pending = append(n.pending, namedShape{name: root, shape: shape})
+	} else {
+		fmt.Fprintf(&b, "type %s %s\n", root, n.goType(shape, root))
+	}
+	for len(n.pending) > 0 {
+		next := n.pending[0]
+		n.pending = n.pending[1:]
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		n.goStruct(&b, next)
+	}
+	return b.String()
+}
+
+// goStruct writes a struct declaration, optional members get omitempty
+func (n *typeNamer) goStruct(b *strings.Builder, ns namedShape) {
+	fmt.Fprintf(b, "type %s struct {\n", ns.name)
+	names := make([]string, len(ns.shape.fields))
+	types := make([]string, len(ns.shape.fields))
+	taken := make(map[string]bool)
+	width, typeWidth := 0, 0
+	for i, f := range ns.shape.fields {
+		field := typeName(f.name)
+		for j := 2; taken[field]; j++ {
+			field = fmt.Sprintf("%s%d", typeName(f.name), j)
+		}
+		taken[field] = true
+		names[i] = field
+		typ := n.goType(f.shape, field)
+		if ns.shape.optional(f) && f.shape.only(shapeObject) && !f.shape.nullable() {
+			typ = "*" + typ
+		}
+		types[i] = typ
+		// fmt pads by runes
+		width = max(width, utf8.RuneCountInString(field))
+		typeWidth = max(typeWidth, utf8.RuneCountInString(typ))
+	}
+	for i, f := range ns.shape.fields {
+		tag := f.name
+		if ns.shape.optional(f) {
+			tag += ",omitempty"
+		}
+		key, _ := json.Marshal(tag)
+		fmt.Fprintf(b, "\t%-*s %-*s `json:%s`\n", width, names[i], typeWidth, types[i], key)
+	}
+	b.WriteString("}\n")
+}
+
+// goType returns the Go type of a shape, null makes scalars and structs pointers
+func (n *typeNamer) goType(s *typeShape, name string) string {
+	var typ string
+	switch {
+	case s.only(shapeBool):
+		typ = "bool"
+	case s.only(shapeInt):
+		typ = "int64"
+	case s.only(shapeFloat):
+		typ = "float64"
+	case s.only(shapeString):
+		typ = "string"
+	case s.only(shapeObject):
+		typ = n.declare(name, s)
+	case s.only(shapeArray):
+		return "[]" + n.goType(s.elem, singular(name))
+	default:
+		return "any"
+	}
+	if s.nullable() {
+		typ = "*" + typ
+	}
+	return typ
+}
+
+// tsTypes renders the shape as TypeScript declarations, the top level type first
+func (n *typeNamer) tsTypes(shape *typeShape, name string) string {
+	var b strings.Builder
+	root := typeName(name)
+	n.taken[root] = true
+	if shape.only(shapeObject) && !shape.nullable() {
+		n.pending = append(n.pending, namedShape{name: root, shape: shape})
+	} else {
+		fmt.Fprintf(&b, "export type %s = %s;\n", root, n.tsType(shape, root))
+	}
+	for len(n.pending) > 0 {
+		next := n.pending[0]
+		n.pending = n.pending[1:]
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "export interface %s {\n", next.name)
+		for _, f := range next.shape.fields {
+			key := f.name
+			if !isIdentifier(key) {
+				quoted, _ := json.Marshal(key)
+				key = string(quoted)
+			}
+			if next.shape.optional(f) {
+				key += "?"
+			}
+			fmt.Fprintf(&b, "  %s: %s;\n", key, n.tsType(f.shape, typeName(f.name)))
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// tsType returns the TypeScript type of a shape, mixed values become a union
+func (n *typeNamer) tsType(s *typeShape, name string) string {
+	var union []string
+	if s.kinds&shapeBool != 0 {
+		union = append(union, "boolean")
+	}
+	if s.kinds&(shapeInt|shapeFloat) != 0 {
+		union = append(union, "number")
+	}
+	if s.kinds&shapeString != 0 {
+		union = append(union, "string")
+	}
+	if s.kinds&shapeObject != 0 {
+		union = append(union, n.declare(name, s))
+	}
+	if s.kinds&shapeArray != 0 {
+		elem := n.tsType(s.elem, singular(name))
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		union = append(union, elem+"[]")
+	}
+	if s.nullable() {
+		union = append(union, "null")
+	}
+	if len(union) == 0 {
+		return "unknown"
+	}
+	return strings.Join(union, " | ")
+}
+
+// writeSchema writes the shape as compact JSON Schema, root adds the $schema keyword
+// integers are integer unless numbers with a fraction were seen as well
+func (s *typeShape) writeSchema(b *bytes.Buffer, root bool) {
+	b.WriteString("{")
+	if root {
+		fmt.Fprintf(b, "%q:%q,", "$schema", schemaDialect)
+	}
+
+	var types []string
+	for _, k := range []struct {
+		kind shapeKind
+		name string
+	}{
+		{shapeNull, "null"}, {shapeBool, "boolean"}, {shapeString, "string"},
+		{shapeObject, "object"}, {shapeArray, "array"},
+	} {
+		if s.kinds&k.kind != 0 {
+			types = append(types, fmt.Sprintf("%q", k.name))
+		}
+	}
+	switch {
+	case s.kinds&shapeFloat != 0:
+		types = append(types, `"number"`)
+	case s.kinds&shapeInt != 0:
+		types = append(types, `"integer"`)
+	}
+	switch len(types) {
+	case 0:
+		// no value seen, e.g. the elements of empty arrays: anything is valid
+		b.WriteString("}")
+		return
+	case 1:
+		b.WriteString(`"type":` + types[0])
+	default:
+		b.WriteString(`"type":[` + strings.Join(types, ",") + "]")
+	}
+
+	if s.kinds&shapeObject != 0 {
+		b.WriteString(`,"properties":{`)
+		var required []string
+		for i, f := range s.fields {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			key, _ := json.Marshal(f.name)
+			b.Write(key)
+			b.WriteString(":")
+			f.shape.writeSchema(b, false)
+			if !s.optional(f) {
+				required = append(required, string(key))
+			}
+		}
+		b.WriteString("}")
+		if len(required) > 0 {
+			b.WriteString(`,"required":[` + strings.Join(required, ",") + "]")
+		}
+	}
+	if s.kinds&shapeArray != 0 {
+		b.WriteString(`,"items":`)
+		s.elem.writeSchema(b, false)
+	}
+	b.WriteString("}")
+}
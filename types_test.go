@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/tidwall/gjson"
+)
+
+// typeSamples are two API responses merged into one type model
+var typeSamples = []string{
+	`{"id":1,"user_name":"a","created_at":"x","tags":["a"],"items":[{"price":1.5,"sku":"a"},{"price":2}],"meta":null}`,
+	`{"id":2,"user_name":"b","tags":[],"items":[],"meta":{"url":"u"}}`,
+}
+
+func TestRenderTypes(t *testing.T) {
+	var values []gjson.Result
+	for _, sample := range typeSamples {
+		values = append(values, gjson.Parse(sample))
+	}
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{typesGo, `type Order struct {
+	ID        int64    ` + "`json:\"id\"`" + `
+	UserName  string   ` + "`json:\"user_name\"`" + `
+	CreatedAt string   ` + "`json:\"created_at,omitempty\"`" + `
+	Tags      []string ` + "`json:\"tags\"`" + `
+	Items     []Item   ` + "`json:\"items\"`" + `
+	Meta      *Meta    ` + "`json:\"meta\"`" + `
+}
+
+type Item struct {
+	Price float64 ` + "`json:\"price\"`" + `
+	Sku   string  ` + "`json:\"sku,omitempty\"`" + `
+}
+
+type Meta struct {
+	URL string ` + "`json:\"url\"`" + `
+}
+`},
+		{typesTS, `export interface Order {
+  id: number;
+  user_name: string;
+  created_at?: string;
+  tags: string[];
+  items: Item[];
+  meta: Meta | null;
+}
+
+export interface Item {
+  price: number;
+  sku?: string;
+}
+
+export interface Meta {
+  url: string;
+}
+`},
+	}
+	for _, tt := range tests {
+		got, err := renderTypes(values, tt.lang, "order")
+		if err != nil {
+			t.Errorf("%s: %v", tt.lang, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: renderTypes =\n%s\nwant\n%s", tt.lang, got, tt.want)
+		}
+	}
+
+	schema, err := renderTypes(values, typesSchema, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		`$schema`:                  `"` + schemaDialect + `"`,
+		`properties.id.type`:       `"integer"`,
+		`properties.items.items`:   `{"type":"object","properties":{"price":{"type":"number"},"sku":{"type":"string"}},"required":["price"]}`,
+		`properties.meta.type`:     `["null","object"]`,
+		`properties.meta.required`: `["url"]`,
+		`required`:                 `["id","user_name","tags","items","meta"]`,
+	} {
+		if got := gjson.Get(schema, path+"|@ugly").Raw; got != want {
+			t.Errorf("schema %s = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestRenderTypesOfArrays(t *testing.T) {
+	tests := []struct {
+		json, lang, want string
+	}{
+		{`[1, 2.5, null]`, typesGo, "type Values []*float64\n"},
+		{`[1, "a"]`, typesTS, "export type Values = (number | string)[];\n"},
+		// the field of a key without upper case keeps the key in its tag
+		{`[{"名前": "a"}]`, typesGo, "type Values []Value\n\ntype Value struct {\n\tX名前 string `json:\"名前\"`\n}\n"},
+	}
+	for _, tt := range tests {
+		got, err := renderTypes([]gjson.Result{gjson.Parse(tt.json)}, tt.lang, "values")
+		if err != nil {
+			t.Errorf("%s: %v", tt.json, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("renderTypes(%s, %s) = %q, want %q", tt.json, tt.lang, got, tt.want)
+		}
+	}
+
+	if _, err := renderTypes(nil, "rust", "x"); err == nil {
+		t.Error("renderTypes accepted an unknown language")
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	tests := map[string]string{
+		"created_at":   "CreatedAt",
+		"user-id":      "UserID",
+		"apiURL":       "ApiURL",
+		"http_url":     "HTTPURL",
+		"2fa":          "N2fa",
+		"":             "Value",
+		"$ref":         "Ref",
+		"über.größe":   "ÜberGröße",
+		"metadata.uid": "MetadataUid",
+		"名前":           "X名前",
+		"_名前":          "X名前",
+	}
+	for key, want := range tests {
+		if got := typeName(key); got != want {
+			t.Errorf("typeName(%q) = %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestSingular(t *testing.T) {
+	tests := map[string]string{
+		"Items":     "Item",
+		"Entries":   "Entry",
+		"Addresses": "Address",
+		"Boxes":     "Box",
+		"Matches":   "Match",
+		"Class":     "ClassItem",
+		"Data":      "DataItem",
+	}
+	for name, want := range tests {
+		if got := singular(name); got != want {
+			t.Errorf("singular(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestTypeModelKey(t *testing.T) {
+	m := newTestModel(t, `{"user":{"id":1,"name":"ann"}}`)
+	titles := []string{"Go", "TypeScript", "JSON Schema", ""}
+	for _, title := range titles {
+		m = send(m, tea.KeyPressMsg{Code: 'm', Mod: tea.ModAlt})
+		if got := m.extractTitle(); title != "" && !strings.HasSuffix(got, " · "+title) || title == "" && got != "JSON Extractor" {
+			t.Errorf("title = %q, want the %q model", got, title)
+		}
+	}
+	m = send(m, tea.KeyPressMsg{Code: 'm', Mod: tea.ModAlt})
+	if content := ansi.Strip(m.extractRaw); !strings.Contains(content, "type User struct") || !strings.Contains(content, "`json:\"name\"`") {
+		t.Errorf("Go model = %q", content)
+	}
+}
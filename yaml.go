@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/quick"
+	"github.com/tidwall/gjson"
+	"gopkg.in/yaml.v3"
+)
+
+// inputFormat is the syntax the input was written in
+type inputFormat int
+
+const (
+	formatJSON inputFormat = iota
+	formatYAML
+	formatJSON5 // JSONC or JSON5
+)
+
+// isYAMLFile reports whether the file extension marks YAML input
+func isYAMLFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// parseYAML converts YAML input into JSON data
+// every document of a multi-document stream becomes a record, the original nodes are kept
+// so that the extractor can render them as YAML with their comments
+func (jp *JSONProcessor) parseYAML() error {
+	dec := yaml.NewDecoder(bytes.NewReader(jp.jsonData))
+	var docs []*JSONProcessor
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+		c := &yamlConverter{nodes: make(map[string]*yaml.Node), seen: make(map[*yaml.Node]struct{}), merges: make(map[*yaml.Node]struct{})}
+		if err := c.convert(nil, &doc); err != nil {
+			return err
+		}
+		docs = append(docs, &JSONProcessor{jsonData: c.buf.Bytes(), format: formatYAML, yamlNodes: c.nodes})
+	}
+	if len(docs) == 0 {
+		return errors.New("input is empty")
+	}
+
+	jp.format = formatYAML
+	jp.source = jp.jsonData
+	if len(docs) == 1 {
+		jp.records = nil
+		jp.jsonData = docs[0].jsonData
+		jp.yamlNodes = docs[0].yamlNodes
+		return nil
+	}
+	var stream bytes.Buffer
+	for _, doc := range docs {
+		stream.Write(doc.jsonData)
+		stream.WriteByte('\n')
+	}
+	jp.jsonData = stream.Bytes()
+	jp.records = docs
+	return nil
+}
+
+// looksLikeYAML reports whether YAML input holds at least one mapping or sequence
+// plain text is also valid YAML, so scalar-only input is not treated as YAML, and input
+// starting like a JSON document is left to the JSON parser so that its errors are reported
+func looksLikeYAML(data []byte) bool {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return false
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	found := false
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return found
+		}
+		if err != nil {
+			return false
+		}
+		if len(doc.Content) > 0 {
+			kind := doc.Content[0].Kind
+			found = found || kind == yaml.MappingNode || kind == yaml.SequenceNode
+		}
+	}
+}
+
+// yamlMaxExpanded is the number of nodes aliases and merge keys may copy,
+// a few nested aliases can otherwise expand into billions of nodes
+const yamlMaxExpanded = 100_000
+
+// yamlConverter writes a YAML node tree as JSON and records the node behind every key
+type yamlConverter struct {
+	buf      bytes.Buffer
+	nodes    map[string]*yaml.Node
+	seen     map[*yaml.Node]struct{} // nodes written at least once
+	merges   map[*yaml.Node]struct{} // merge keys whose !!merge tag was cleared
+	expanded int                     // nodes written again as copies
+}
+
+// convert writes node as JSON, prefix is the path of the node as built by extractKeys
+func (c *yamlConverter) convert(prefix jsonPath, node *yaml.Node) error {
+	if node.Kind == yaml.AliasNode {
+		return c.convert(prefix, node.Alias)
+	}
+	if _, ok := c.seen[node]; ok {
+		if c.expanded++; c.expanded > yamlMaxExpanded {
+			return fmt.Errorf("line %d: aliases expand to more than %d nodes", node.Line, yamlMaxExpanded)
+		}
+	}
+	c.seen[node] = struct{}{}
+	if key := prefix.String(); c.nodes[key] == nil {
+		c.nodes[key] = node
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			c.buf.WriteString("null")
+			return nil
+		}
+		return c.convert(prefix, node.Content[0])
+
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			// an explicit !!merge tag would be written out when the node is encoded again
+			if key := node.Content[i]; key.Tag == "!!merge" {
+				c.merges[key] = struct{}{}
+				key.Tag = ""
+			}
+		}
+		c.buf.WriteByte('{')
+		for i, pair := range c.mappingPairs(node) {
+			if i > 0 {
+				c.buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(pair[0].Value)
+			c.buf.Write(key)
+			c.buf.WriteByte(':')
+			if err := c.convert(prefix.child(pair[0].Value), pair[1]); err != nil {
+				return err
+			}
+		}
+		c.buf.WriteByte('}')
+
+	case yaml.SequenceNode:
+		c.buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				c.buf.WriteByte(',')
+			}
+			if err := c.convert(prefix.element(i), item); err != nil {
+				return err
+			}
+		}
+		c.buf.WriteByte(']')
+
+	case yaml.ScalarNode:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			// values without a JSON representation such as .inf or .nan are kept as strings
+			raw, _ = json.Marshal(node.Value)
+		}
+		c.buf.Write(raw)
+	}
+	return nil
+}
+
+// mappingPairs returns the key/value pairs of a mapping with merge keys (<<) resolved
+// explicit keys take precedence over merged ones and the first merged mapping wins
+func (c *yamlConverter) mappingPairs(node *yaml.Node) [][2]*yaml.Node {
+	explicit := make(map[string]struct{})
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !c.isMerge(node.Content[i]) {
+			explicit[node.Content[i].Value] = struct{}{}
+		}
+	}
+
+	var pairs [][2]*yaml.Node
+	merged := make(map[string]struct{})
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if !c.isMerge(key) {
+			pairs = append(pairs, [2]*yaml.Node{key, value})
+			continue
+		}
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, source := range sources {
+			for source.Kind == yaml.AliasNode {
+				source = source.Alias
+			}
+			if source.Kind != yaml.MappingNode {
+				continue
+			}
+			for _, pair := range c.mappingPairs(source) {
+				name := pair[0].Value
+				_, isExplicit := explicit[name]
+				_, isMerged := merged[name]
+				if !isExplicit && !isMerged {
+					merged[name] = struct{}{}
+					pairs = append(pairs, pair)
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// isMerge reports whether key is a merge key, also after convert cleared its tag
+func (c *yamlConverter) isMerge(key *yaml.Node) bool {
+	_, cleared := c.merges[key]
+	return cleared || key.ShortTag() == "!!merge"
+}
+
+// toYAML renders the value of a key as YAML
+// nodes read from YAML input keep their comments, other values are converted from JSON
+func (jp *JSONProcessor) toYAML(key string) string {
+	if node, ok := jp.yamlNodes[key]; ok {
+		if out, err := encodeYAML(node); err == nil {
+			return out
+		}
+	}
+
+	var results []gjson.Result
+	if key == "" {
+		results = []gjson.Result{gjson.ParseBytes(jp.jsonData)}
+	} else {
+		var ok bool
+		if results, ok = queryResults(key, jp.jsonData); !ok {
+			return "Query failed. No matching data found."
+		}
+	}
+
+	var docs []string
+	for _, result := range results {
+		out, err := encodeYAML(jsonToYAMLNode(result))
+		if err != nil {
+			return err.Error()
+		}
+		docs = append(docs, out)
+	}
+	return strings.Join(docs, "---\n")
+}
+
+// encodeYAML encodes a node as YAML with two-space indentation
+func encodeYAML(node *yaml.Node) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// jsonToYAMLNode converts a JSON value into a YAML node keeping the key order
+func jsonToYAMLNode(value gjson.Result) *yaml.Node {
+	switch {
+	case value.IsObject():
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		value.ForEach(func(key, val gjson.Result) bool {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.String()},
+				jsonToYAMLNode(val))
+			return true
+		})
+		return node
+	case value.IsArray():
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		value.ForEach(func(_, val gjson.Result) bool {
+			node.Content = append(node.Content, jsonToYAMLNode(val))
+			return true
+		})
+		return node
+	}
+
+	switch value.Type {
+	case gjson.String:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value.String()}
+	case gjson.Number:
+		if _, err := strconv.ParseInt(value.Raw, 10, 64); err == nil {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: value.Raw}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: value.Raw}
+	case gjson.True, gjson.False:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: value.Raw}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+}
+
+// highlightYAML applies syntax highlighting to YAML
+func highlightYAML(yamlData string) string {
+	var highlighted bytes.Buffer
+	err := quick.Highlight(&highlighted, yamlData, "yaml", "terminal", "monokai")
+	if err != nil {
+		return yamlData
+	}
+	return highlighted.String()
+}
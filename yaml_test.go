@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"key order", "b: 1\na: 2\nc: 3\n", `{"b":1,"a":2,"c":3}`},
+		{"scalars", "i: 7\nf: 1.5\nt: true\nn: null\ns: '7'\ninf: .inf\n", `{"i":7,"f":1.5,"t":true,"n":null,"s":"7","inf":".inf"}`},
+		{"sequences", "items:\n  - name: a\n  - name: b\n", `{"items":[{"name":"a"},{"name":"b"}]}`},
+		{"aliases", "base: &b {x: 1}\ncopy: *b\n", `{"base":{"x":1},"copy":{"x":1}}`},
+		{"empty document", "---\n", `null`},
+		{"merge keys", "base: &b {x: 1, y: 2}\ncopy:\n  <<: *b\n  y: 3\n", `{"base":{"x":1,"y":2},"copy":{"x":1,"y":3}}`},
+		{"merged merges", "a: &a {x: 1}\nb: &b {<<: *a, y: 2}\nc: {<<: [*b, {z: 3}]}\n", `{"a":{"x":1},"b":{"x":1,"y":2},"c":{"x":1,"y":2,"z":3}}`},
+		{"explicit merge tag", "base: &b {x: 1}\ncopy: {!!merge <<: *b}\n", `{"base":{"x":1},"copy":{"x":1}}`},
+	}
+	for _, tt := range tests {
+		jp := &JSONProcessor{jsonData: []byte(tt.in)}
+		if err := jp.parseYAML(); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := string(jp.jsonData); got != tt.want || jp.format != formatYAML {
+			t.Errorf("%s: parseYAML = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "a: [1\n", "a: 1\n a: 2\n"} {
+		jp := &JSONProcessor{jsonData: []byte(bad)}
+		if err := jp.parseYAML(); err == nil {
+			t.Errorf("parseYAML(%q) = %s, want an error", bad, jp.jsonData)
+		}
+	}
+
+	// a merge key is written back as it was read
+	jp := &JSONProcessor{jsonData: []byte("base: &b {x: 1}\ncopy:\n  <<: *b\n")}
+	if err := jp.parseYAML(); err != nil {
+		t.Fatal(err)
+	}
+	if got := jp.toYAML("copy"); got != "<<: *b" {
+		t.Errorf("toYAML(copy) = %q", got)
+	}
+}
+
+func TestYAMLAliasLimit(t *testing.T) {
+	// every level doubles the nodes of the one before, the last holds 2^30 copies of a
+	var b strings.Builder
+	b.WriteString("l0: &l0 a\n")
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&b, "l%d: &l%d [*l%d, *l%d]\n", i, i, i-1, i-1)
+	}
+	jp := &JSONProcessor{jsonData: []byte(b.String())}
+	err := jp.parseYAML()
+	if err == nil || !strings.Contains(err.Error(), "aliases expand to more than 100000 nodes") {
+		t.Errorf("error = %v", err)
+	}
+
+	// merged values count as copies as well
+	b.Reset()
+	b.WriteString("m0: &m0 {a: 1, b: 1}\n")
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&b, "m%d: &m%d {a: {<<: *m%d}, b: {<<: *m%d}}\n", i, i, i-1, i-1)
+	}
+	jp = &JSONProcessor{jsonData: []byte(b.String())}
+	if err := jp.parseYAML(); err == nil {
+		t.Error("nested merge keys expanded without a limit")
+	}
+
+	// a few copies stay well below the limit
+	jp = &JSONProcessor{jsonData: []byte("l0: &l0 [1, 2]\nl1: &l1 [*l0, *l0]\nl2: [*l1, *l1]\n")}
+	if err := jp.parseYAML(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseYAMLDocuments(t *testing.T) {
+	jp := &JSONProcessor{jsonData: []byte("kind: Service\n---\nkind: Deployment\nspec:\n  replicas: 2\n")}
+	if err := jp.parseYAML(); err != nil {
+		t.Fatal(err)
+	}
+	if len(jp.records) != 2 {
+		t.Fatalf("got %d documents, want 2", len(jp.records))
+	}
+	if got := string(jp.records[1].jsonData); got != `{"kind":"Deployment","spec":{"replicas":2}}` {
+		t.Errorf("second document = %s", got)
+	}
+	if got := jp.records[1].toYAML("spec"); got != "replicas: 2" {
+		t.Errorf("toYAML(spec) = %q", got)
+	}
+}
+
+func TestLooksLikeYAML(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{"a: 1\n", true},
+		{"- a\n- b\n", true},
+		{"a: 1\n---\nb: 2\n", true},
+		{"just some text\n", false},
+		{"42\n", false},
+		{`{"a": 1,}`, false},
+		{"[1, 2", false},
+		{"a: [1\n", false},
+	}
+	for _, tt := range tests {
+		if got := looksLikeYAML([]byte(tt.data)); got != tt.want {
+			t.Errorf("looksLikeYAML(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestToYAML(t *testing.T) {
+	in := "# the service\nname: web # shown in the list\nports:\n  - 80\n  - 443\n"
+	jp := &JSONProcessor{jsonData: []byte(in)}
+	if err := jp.parseYAML(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key, want string
+	}{
+		{"", strings.TrimSuffix(in, "\n")},
+		{"name", "web # shown in the list"},
+		{"ports", "- 80\n- 443"},
+		{"ports[1]", "443"},
+	}
+	for _, tt := range tests {
+		if got := jp.toYAML(tt.key); got != tt.want {
+			t.Errorf("toYAML(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	// JSON input is converted keeping its key order and types
+	jp = &JSONProcessor{jsonData: []byte(`{"z":{"b":[1,2.5,"3",true,null]},"a":"x"}`)}
+	want := "z:\n  b:\n    - 1\n    - 2.5\n    - \"3\"\n    - true\n    - null\na: x"
+	if got := jp.toYAML(""); got != want {
+		t.Errorf("toYAML = %q, want %q", got, want)
+	}
+	if got := jp.toYAML("missing"); !strings.HasPrefix(got, "Query failed") {
+		t.Errorf("toYAML(missing) = %q", got)
+	}
+}
+
+func TestLoadYAMLInput(t *testing.T) {
+	manifest := "apiVersion: v1\nkind: Pod\n"
+	for _, name := range []string{"pod.yaml", "pod.yml", "pod"} {
+		jp, err := loadInput(loadOptions{path: writeInput(t, name, manifest)}, nil)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if jp.format != formatYAML || string(jp.jsonData) != `{"apiVersion":"v1","kind":"Pod"}` {
+			t.Errorf("%s: read %s as format %d", name, jp.jsonData, jp.format)
+		}
+	}
+	// broken JSON reports the JSON error instead of being read as YAML
+	if _, err := loadInput(loadOptions{path: writeInput(t, "a.json", `{"a":}`)}, nil); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Errorf("error %v, want a JSON error", err)
+	}
+}
+
+func TestYAMLOutputToggle(t *testing.T) {
+	m := newTestModel(t, `{"a":{"b":1}}`)
+	if m.yamlOutput || m.extractTitle() != "JSON Extractor" {
+		t.Fatal("JSON input starts with YAML output")
+	}
+	m = send(m, ctrlKey('y'))
+	if !m.yamlOutput || m.extractTitle() != "JSON Extractor · YAML" {
+		t.Error("ctrl+y did not switch to YAML output")
+	}
+}